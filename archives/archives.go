@@ -0,0 +1,119 @@
+package archives
+
+import (
+	"io"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// Archive is a rotated file of a log.
+type Archive struct {
+	Path        string
+	Size        int64
+	ModTime     time.Time
+	Compression string
+	Match       *filename.Match
+}
+
+func walk(fs fsys.FileSystem, dir string, depth int, visit func(string, os.FileInfo)) error {
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		name := path.Join(dir, info.Name())
+		if info.IsDir() {
+			if depth > 0 {
+				if err := walk(fs, name, depth-1, visit); err != nil {
+					return err
+				}
+			}
+		} else if depth == 0 {
+			visit(name, info)
+		}
+	}
+	return nil
+}
+
+func less(a *Archive, b *Archive, shifted bool) bool {
+	if shifted {
+		return a.Match.Index > b.Match.Index
+	}
+	if !a.Match.Time.Equal(b.Match.Time) {
+		return a.Match.Time.Before(b.Match.Time)
+	}
+	if a.Match.Counter != b.Match.Counter {
+		return a.Match.Counter < b.Match.Counter
+	}
+	if a.Match.Index != b.Match.Index {
+		return a.Match.Index < b.Match.Index
+	}
+	return a.Path < b.Path
+}
+
+// List returns the archives of a state, from the oldest to the newest. They're
+// ordered by the time and the counter parsed from their names or, when
+// archives are shifted, by their position.
+func List(fs fsys.FileSystem, s *state.State) ([]*Archive, error) {
+	p, err := filename.NewParser(s)
+	if err != nil {
+		return nil, utils.Wrap(err, "Cannot parse the configuration")
+	}
+	archives := make([]*Archive, 0)
+	if !utils.ExistsIn(fs, p.Root()) {
+		return archives, nil
+	}
+	err = walk(fs, p.Root(), p.Depth(), func(name string, info os.FileInfo) {
+		if m, ok := p.Parse(name); ok {
+			archives = append(archives, &Archive{
+				Path:        name,
+				Size:        info.Size(),
+				ModTime:     info.ModTime(),
+				Compression: m.Compression,
+				Match:       m,
+			})
+		}
+	})
+	if err != nil {
+		return nil, utils.Wrapf(err, "Cannot list %s", p.Root())
+	}
+	sort.SliceStable(archives, func(i, j int) bool { return less(archives[i], archives[j], p.Shifted()) })
+	return archives, nil
+}
+
+func (a *Archive) PrettyTime() string {
+	if a.Match.Time.IsZero() {
+		return "-"
+	}
+	return a.Match.Time.Format(time.RFC822)
+}
+
+func (a *Archive) PrettyCompression() string {
+	if a.Compression == "" {
+		return "-"
+	}
+	return a.Compression
+}
+
+func WriteArchives(w io.Writer, archives []*Archive) error {
+	t, err := template.New("files").Parse("FILE\tTIME\tSIZE\tCOMPRESSION\n" +
+		"{{range .}}{{.Path}}\t{{.PrettyTime}}\t{{.Size}}\t{{.PrettyCompression}}\n{{end}}")
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', tabwriter.TabIndent)
+	err = t.Execute(tw, archives)
+	if err != nil {
+		return err
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,89 @@
+package archives
+
+import (
+	"bytes"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func paths(archives []*Archive) []string {
+	names := make([]string, 0)
+	for _, a := range archives {
+		names = append(names, a.Path)
+	}
+	return names
+}
+
+func memFiles(names ...string) *fsys.MemFileSystem {
+	fs := fsys.NewMemFileSystem()
+	for _, name := range names {
+		fs.MkdirAll(path.Dir(name), 0755)
+		fs.WriteFile(name, []byte(name), 0644)
+	}
+	return fs
+}
+
+func TestListSortsArchivesChronologically(t *testing.T) {
+	fs := memFiles(
+		"/logs/app.log",
+		"/logs/app.20181209-1.log",
+		"/logs/app.20181210.log.gz",
+		"/logs/app.20181209.log",
+		"/logs/app.2018120.log",
+		"/logs/other.20181209.log",
+	)
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%Y%m%d", UTC: true}}
+	archives, err := List(fs, s)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/logs/app.20181209.log", "/logs/app.20181209-1.log", "/logs/app.20181210.log.gz"}, paths(archives))
+	assert.Equal(t, "gzip", archives[2].Compression)
+	assert.Equal(t, int64(len("/logs/app.20181210.log.gz")), archives[2].Size)
+	assert.Equal(t, time.Date(2018, 12, 10, 0, 0, 0, 0, time.UTC), archives[2].Match.Time)
+}
+
+func TestListSortsByCounter(t *testing.T) {
+	fs := memFiles("/logs/app.10.log", "/logs/app.9.log", "/logs/app.11.log")
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%c"}}
+	archives, err := List(fs, s)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/logs/app.9.log", "/logs/app.10.log", "/logs/app.11.log"}, paths(archives))
+}
+
+func TestListShiftedArchives(t *testing.T) {
+	fs := memFiles("/logs/app.log.1", "/logs/app.log.10.gz", "/logs/app.log.2")
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Naming: state.NamingShift}}
+	archives, err := List(fs, s)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/logs/app.log.10.gz", "/logs/app.log.2", "/logs/app.log.1"}, paths(archives))
+}
+
+func TestListWalksTheArchiveDir(t *testing.T) {
+	fs := memFiles("/archive/2019/01/app.01.log", "/archive/2018/12/app.31.log", "/archive/app.01.log", "/logs/app.log")
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%d", ArchiveDir: "/archive/%Y/%m", UTC: true}}
+	archives, err := List(fs, s)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/archive/2018/12/app.31.log", "/archive/2019/01/app.01.log"}, paths(archives))
+}
+
+func TestListWithoutArchives(t *testing.T) {
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%c", ArchiveDir: "/archive"}}
+	archives, err := List(fsys.NewMemFileSystem(), s)
+	assert.NoError(t, err)
+	assert.Empty(t, archives)
+}
+
+func TestWriteArchives(t *testing.T) {
+	fs := memFiles("/logs/app.20181209.log.zst", "/logs/app.x.log")
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%Y%m%d", UTC: true}}
+	archives, err := List(fs, s)
+	assert.NoError(t, err)
+	var b bytes.Buffer
+	assert.NoError(t, WriteArchives(&b, archives))
+	assert.Equal(t, "FILE                       TIME                SIZE COMPRESSION\n"+
+		"/logs/app.20181209.log.zst 09 Dec 18 00:00 UTC 26   zstd\n", b.String())
+}
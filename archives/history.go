@@ -0,0 +1,128 @@
+package archives
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// File is an archive or the log file, with the time span of its content: the
+// logs written after the end of the previous file and until its last write.
+// Start is zero for the oldest file.
+type File struct {
+	Path        string
+	Compression string
+	Start       time.Time
+	End         time.Time
+}
+
+// end returns the latest between the time in the name of the archive, which
+// may be truncated by the suffix, and its modification time.
+func end(a *Archive) time.Time {
+	if a.Match.Time.After(a.ModTime) {
+		return a.Match.Time
+	}
+	return a.ModTime
+}
+
+// History returns the archives of a state followed by its log file, if it
+// exists, from the oldest.
+func History(fs fsys.FileSystem, s *state.State) ([]*File, error) {
+	list, err := List(fs, s)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*File, 0, len(list)+1)
+	var start time.Time
+	for _, a := range list {
+		f := &File{Path: a.Path, Compression: a.Compression, Start: start, End: end(a)}
+		files = append(files, f)
+		start = f.End
+	}
+	info, err := fs.Stat(s.FullName)
+	if err == nil {
+		files = append(files, &File{Path: s.FullName, Start: start, End: info.ModTime()})
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Between returns the files whose content may have been written between since
+// and until; zero times are not bounds.
+func Between(files []*File, since time.Time, until time.Time) []*File {
+	selected := make([]*File, 0)
+	for _, f := range files {
+		if !since.IsZero() && f.End.Before(since) {
+			continue
+		}
+		if !until.IsZero() && f.Start.After(until) {
+			continue
+		}
+		selected = append(selected, f)
+	}
+	return selected
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r *readCloser) Close() error {
+	return r.close()
+}
+
+// Open returns a reader of the content of a file, decompressing it.
+func Open(fs fsys.FileSystem, f *File) (io.ReadCloser, error) {
+	file, err := fs.OpenFile(f.Path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch f.Compression {
+	case "":
+		return file, nil
+	case "gzip":
+		r, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, utils.Wrapf(err, "Cannot decompress %s", f.Path)
+		}
+		return &readCloser{r, func() error { r.Close(); return file.Close() }}, nil
+	case "bzip2":
+		return &readCloser{bzip2.NewReader(file), file.Close}, nil
+	case "zstd":
+		r, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, utils.Wrapf(err, "Cannot decompress %s", f.Path)
+		}
+		return &readCloser{r, func() error { r.Close(); return file.Close() }}, nil
+	}
+	file.Close()
+	return nil, fmt.Errorf("Unsupported compression %s", f.Compression)
+}
+
+// Cat writes the content of the files to w, decompressing them.
+func Cat(w io.Writer, fs fsys.FileSystem, files []*File) error {
+	for _, f := range files {
+		r, err := Open(fs, f)
+		if err != nil {
+			return utils.Wrapf(err, "Cannot open %s", f.Path)
+		}
+		_, err = io.Copy(w, r)
+		r.Close()
+		if err != nil {
+			return utils.Wrapf(err, "Cannot read %s", f.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package archives
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipped(t *testing.T, content string) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, err := w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+func zstded(t *testing.T, content string) []byte {
+	var b bytes.Buffer
+	w, err := zstd.NewWriter(&b)
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+func TestHistory(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	fs.SetClock(func() time.Time { return time.Date(2018, 12, 8, 0, 0, 1, 0, time.UTC) })
+	fs.WriteFile("/logs/app.20181208.log.gz", gzipped(t, "day 7\n"), 0644)
+	fs.SetClock(func() time.Time { return time.Date(2018, 12, 9, 0, 0, 1, 0, time.UTC) })
+	fs.WriteFile("/logs/app.20181209.log.zst", zstded(t, "day 8\n"), 0644)
+	fs.SetClock(func() time.Time { return time.Date(2018, 12, 10, 0, 0, 1, 0, time.UTC) })
+	fs.WriteFile("/logs/app.20181210.log", []byte("day 9\n"), 0644)
+	fs.WriteFile("/logs/app.log", []byte("day 10\n"), 0644)
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%Y%m%d", UTC: true}}
+	files, err := History(fs, s)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(files))
+	assert.True(t, files[0].Start.IsZero())
+	assert.Equal(t, time.Date(2018, 12, 8, 0, 0, 1, 0, time.UTC), files[0].End)
+	assert.Equal(t, files[0].End, files[1].Start)
+	assert.Equal(t, "/logs/app.log", files[3].Path)
+	assert.Equal(t, files[2].End, files[3].Start)
+	var b bytes.Buffer
+	assert.NoError(t, Cat(&b, fs, files))
+	assert.Equal(t, "day 7\nday 8\nday 9\nday 10\n", b.String())
+}
+
+func TestBetweenSelectsFiles(t *testing.T) {
+	day := func(d int, h int) time.Time { return time.Date(2018, 12, d, h, 0, 0, 0, time.UTC) }
+	files := []*File{
+		{Path: "/logs/app.20181208.log", End: day(8, 0)},
+		{Path: "/logs/app.20181209.log", Start: day(8, 0), End: day(9, 0)},
+		{Path: "/logs/app.20181210.log", Start: day(9, 0), End: day(10, 0)},
+		{Path: "/logs/app.log", Start: day(10, 0), End: day(10, 1)},
+	}
+	for _, c := range []struct {
+		since    time.Time
+		until    time.Time
+		expected []*File
+	}{
+		{time.Time{}, time.Time{}, files},
+		{day(8, 12), time.Time{}, files[1:]},
+		{time.Time{}, day(8, 12), files[:2]},
+		{day(8, 12), day(8, 13), files[1:2]},
+	} {
+		assert.Equal(t, c.expected, Between(files, c.since, c.until))
+	}
+}
+
+func TestOpenFailsOnCorruptedArchives(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.WriteFile("/app.1.log.gz", []byte("not gzip"), 0644)
+	_, err := Open(fs, &File{Path: "/app.1.log.gz", Compression: "gzip"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package archives
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// Rename is a file to move when a log file is renamed.
+type Rename struct {
+	From string
+	To   string
+}
+
+func trimExt(file string) (string, string) {
+	ext := path.Ext(file)
+	return strings.TrimSuffix(file, ext), ext
+}
+
+// renamedArchive returns the name of an archive of the state once the log file
+// is renamed to fullName: the name of the log file is replaced and, unless the
+// archive directory is absolute, the archive follows the log file.
+func renamedArchive(s *state.State, a *Archive, fullName string) (string, error) {
+	oldDir, oldFile := path.Split(s.FullName)
+	newDir, newFile := path.Split(fullName)
+	dir, file := path.Split(a.Path)
+	if !path.IsAbs(s.Config.ArchiveDir) {
+		rel, err := filepath.Rel(oldDir, dir)
+		if err != nil {
+			return "", err
+		}
+		dir = path.Join(newDir, rel)
+	}
+	name, compression := file, ""
+	if a.Compression != "" {
+		name, compression = trimExt(file)
+	}
+	if s.Config.Naming == state.NamingShift {
+		return path.Join(dir, newFile+strings.TrimPrefix(name, oldFile)+compression), nil
+	}
+	oldBase, oldExt := trimExt(oldFile)
+	newBase, newExt := trimExt(newFile)
+	suffix := strings.TrimSuffix(strings.TrimPrefix(name, oldBase), oldExt)
+	return path.Join(dir, newBase+suffix+newExt+compression), nil
+}
+
+// Renames returns the files to move when the log file of the state is renamed
+// to fullName: the log file, if it exists, and its archives.
+func Renames(fs fsys.FileSystem, s *state.State, fullName string) ([]*Rename, error) {
+	renames := make([]*Rename, 0)
+	if utils.ExistsIn(fs, s.FullName) {
+		renames = append(renames, &Rename{s.FullName, fullName})
+	}
+	list, err := List(fs, s)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range list {
+		to, err := renamedArchive(s, a, fullName)
+		if err != nil {
+			return nil, err
+		}
+		renames = append(renames, &Rename{a.Path, to})
+	}
+	return renames, nil
+}
+
+// Reverse returns the renames that undo renames.
+func Reverse(renames []*Rename) []*Rename {
+	reversed := make([]*Rename, 0, len(renames))
+	for i := len(renames) - 1; i >= 0; i-- {
+		reversed = append(reversed, &Rename{renames[i].To, renames[i].From})
+	}
+	return reversed
+}
+
+// Move performs the renames, creating directories with dirMode; nothing is
+// moved if a destination already exists. If a rename fails the completed ones
+// are undone, so that the files keep the names known to the state.
+func Move(fs fsys.FileSystem, renames []*Rename, dirMode os.FileMode) error {
+	for _, r := range renames {
+		if utils.ExistsIn(fs, r.To) {
+			return fmt.Errorf("%s already exists", r.To)
+		}
+	}
+	for i, r := range renames {
+		err := utils.Move(fs, r.From, r.To, dirMode)
+		if err != nil {
+			for _, u := range Reverse(renames[:i]) {
+				utils.Move(fs, u.From, u.To, dirMode)
+			}
+			return utils.Wrapf(err, "Cannot move %s to %s", r.From, r.To)
+		}
+	}
+	return nil
+}
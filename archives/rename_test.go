@@ -0,0 +1,89 @@
+package archives
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func renamePairs(renames []*Rename) [][2]string {
+	pairs := make([][2]string, 0)
+	for _, r := range renames {
+		pairs = append(pairs, [2]string{r.From, r.To})
+	}
+	return pairs
+}
+
+func TestRenames(t *testing.T) {
+	fs := memFiles("/logs/app.log", "/logs/app.20181209.log", "/logs/app.20181209-1.log", "/logs/app.20181210.log.gz")
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%Y%m%d"}}
+	renames, err := Renames(fs, s, "/srv/web.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{
+		{"/logs/app.log", "/srv/web.txt"},
+		{"/logs/app.20181209.log", "/srv/web.20181209.txt"},
+		{"/logs/app.20181209-1.log", "/srv/web.20181209-1.txt"},
+		{"/logs/app.20181210.log.gz", "/srv/web.20181210.txt.gz"},
+	}, renamePairs(renames))
+}
+
+func TestRenamesWithArchiveDir(t *testing.T) {
+	fs := memFiles("/logs/old/2018/app.1.log", "/archive/app.2.log")
+	relative := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%c", ArchiveDir: "old/%Y"}}
+	renames, err := Renames(fs, relative, "/srv/web.log")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{{"/logs/old/2018/app.1.log", "/srv/old/2018/web.1.log"}}, renamePairs(renames))
+	absolute := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%c", ArchiveDir: "/archive"}}
+	renames, err = Renames(fs, absolute, "/srv/web.log")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{{"/archive/app.2.log", "/archive/web.2.log"}}, renamePairs(renames))
+}
+
+func TestRenamesShiftedArchives(t *testing.T) {
+	fs := memFiles("/logs/app.log.1", "/logs/app.log.2.gz")
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Naming: state.NamingShift}}
+	renames, err := Renames(fs, s, "/logs/web.log")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{
+		{"/logs/app.log.2.gz", "/logs/web.log.2.gz"},
+		{"/logs/app.log.1", "/logs/web.log.1"},
+	}, renamePairs(renames))
+}
+
+func TestMove(t *testing.T) {
+	fs := memFiles("/logs/app.log", "/logs/app.1.log")
+	renames := []*Rename{{"/logs/app.log", "/srv/web.log"}, {"/logs/app.1.log", "/srv/web.1.log"}}
+	assert.NoError(t, Move(fs, renames, 0755))
+	assert.True(t, utils.ExistsIn(fs, "/srv/web.log"))
+	assert.True(t, utils.ExistsIn(fs, "/srv/web.1.log"))
+	assert.False(t, utils.ExistsIn(fs, "/logs/app.log"))
+}
+
+func TestMoveFailsIfADestinationExists(t *testing.T) {
+	fs := memFiles("/logs/app.log", "/logs/app.1.log", "/srv/web.1.log")
+	renames := []*Rename{{"/logs/app.log", "/srv/web.log"}, {"/logs/app.1.log", "/srv/web.1.log"}}
+	assert.Error(t, Move(fs, renames, 0755))
+	assert.True(t, utils.ExistsIn(fs, "/logs/app.log"))
+	assert.False(t, utils.ExistsIn(fs, "/srv/web.log"))
+}
+
+func TestMoveUndoesCompletedRenamesOnFailure(t *testing.T) {
+	fs := memFiles("/logs/app.log", "/logs/app.1.log", "/logs/app.2.log")
+	fs.InjectFault(fsys.OpRename, "/logs/app.2.log", errors.New("failure"))
+	renames := []*Rename{{"/logs/app.log", "/srv/web.log"}, {"/logs/app.1.log", "/srv/web.1.log"}, {"/logs/app.2.log", "/srv/web.2.log"}}
+	assert.Error(t, Move(fs, renames, 0755))
+	for _, name := range []string{"/logs/app.log", "/logs/app.1.log", "/logs/app.2.log"} {
+		assert.True(t, utils.ExistsIn(fs, name), name)
+	}
+	assert.False(t, utils.ExistsIn(fs, "/srv/web.log"))
+	assert.False(t, utils.ExistsIn(fs, "/srv/web.1.log"))
+}
+
+func TestReverse(t *testing.T) {
+	renames := []*Rename{{"/a", "/b"}, {"/c", "/d"}}
+	assert.Equal(t, [][2]string{{"/d", "/c"}, {"/b", "/a"}}, renamePairs(Reverse(renames)))
+}
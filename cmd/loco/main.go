@@ -1,40 +1,250 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kingpin"
+	"github.com/lorenzobenvenuti/loco/archives"
+	"github.com/lorenzobenvenuti/loco/collector"
 	"github.com/lorenzobenvenuti/loco/defaults"
+	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/fsys"
 	"github.com/lorenzobenvenuti/loco/intervals"
 	"github.com/lorenzobenvenuti/loco/logwriter"
+	"github.com/lorenzobenvenuti/loco/manifest"
+	"github.com/lorenzobenvenuti/loco/search"
+	"github.com/lorenzobenvenuti/loco/simulate"
 	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/status"
+	"github.com/lorenzobenvenuti/loco/tail"
+	"github.com/lorenzobenvenuti/loco/utils"
 )
 
 var logger = log.New(os.Stderr, "", 0)
 
-func createConfig(file string, interval string, suffix string) {
-	var err error
-	absPath, err := filepath.Abs(file)
+func validateConfig(c *state.Config) {
+	if c.Retention < 0 {
+		logger.Fatalf("Invalid retention: %d", c.Retention)
+	}
+	if err := state.ValidatePolicies(c); err != nil {
+		logger.Fatal(err)
+	}
+	err := filename.ValidateDir(c.ArchiveDir)
 	if err != nil {
-		logger.Fatalf("Cannot convert path %s: %s", file, err)
+		logger.Fatal(err)
 	}
-	var duration time.Duration
-	if interval != "" {
-		duration, err = intervals.Parse(interval)
+	if c.Owner != "" {
+		if _, err := utils.LookupUid(c.Owner); err != nil {
+			logger.Fatalf("Invalid owner %s: %s", c.Owner, err)
+		}
+	}
+	if c.Group != "" {
+		if _, err := utils.LookupGid(c.Group); err != nil {
+			logger.Fatalf("Invalid group %s: %s", c.Group, err)
+		}
+	}
+	if c.Naming == state.NamingShift {
+		return
+	}
+	warnings, err := filename.Validate(c.Suffix, c.Interval)
+	if err != nil {
+		logger.Fatalf("Invalid suffix: %s", err)
+	}
+	for _, w := range warnings {
+		logger.Printf("Warning: %s", w)
+	}
+}
+
+type configOptions struct {
+	interval   string
+	suffix     string
+	collision  string
+	utc        bool
+	naming     string
+	retention  int
+	archiveDir string
+	dirMode    string
+	fileMode   string
+	owner      string
+	group      string
+	labels     map[string]string
+	reset      bool
+	// set holds the flags given on the command line: only those are changed
+	// when updating an existing configuration.
+	set map[string]bool
+}
+
+func (o *configOptions) mark(flag string) kingpin.Action {
+	return func(*kingpin.ParseContext) error {
+		o.set[flag] = true
+		return nil
+	}
+}
+
+// configFlags adds the settings of a configuration as flags of cmd.
+func configFlags(cmd *kingpin.CmdClause, o *configOptions) {
+	cmd.Flag("interval", "Rotate interval").Short('i').Action(o.mark("interval")).StringVar(&o.interval)
+	cmd.Flag("suffix", "Rotated file suffix").Short('s').Action(o.mark("suffix")).StringVar(&o.suffix)
+	cmd.Flag("collision", "Policy when a rotated file already exists").
+		Short('c').
+		Action(o.mark("collision")).
+		EnumVar(&o.collision, state.CollisionUnique, state.CollisionAppend, state.CollisionFail)
+	cmd.Flag("utc", "Render time tokens of the suffix in UTC").Action(o.mark("utc")).BoolVar(&o.utc)
+	cmd.Flag("naming", "Naming of rotated files: suffix, or shift to rename file.1 to file.2 and so on").
+		Short('n').
+		Action(o.mark("naming")).
+		EnumVar(&o.naming, state.NamingSuffix, state.NamingShift)
+	cmd.Flag("retention", "Number of rotated files to keep (0 keeps all of them)").
+		Short('r').
+		Action(o.mark("retention")).
+		IntVar(&o.retention)
+	cmd.Flag("archive-dir", "Directory of rotated files, relative to the log file; accepts the suffix tokens").
+		Short('a').
+		Action(o.mark("archive-dir")).
+		StringVar(&o.archiveDir)
+	cmd.Flag("dir-mode", "Permissions of the created archive and state directories, in octal").
+		Action(o.mark("dir-mode")).
+		StringVar(&o.dirMode)
+	cmd.Flag("file-mode", "Permissions of log files and archives, in octal").
+		Action(o.mark("file-mode")).
+		StringVar(&o.fileMode)
+	cmd.Flag("owner", "Owner of log files and archives (name or id)").Action(o.mark("owner")).StringVar(&o.owner)
+	cmd.Flag("group", "Group of log files and archives (name or id)").Action(o.mark("group")).StringVar(&o.group)
+	cmd.Flag("label", "Label of the log file (key=value, an empty value removes it), can be repeated").
+		Short('l').
+		Action(o.mark("label")).
+		StringMapVar(&o.labels)
+}
+
+func parseMode(mode string) os.FileMode {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 0777 {
+		logger.Fatalf("Invalid mode %s", mode)
+	}
+	return os.FileMode(m)
+}
+
+func updateLabels(labels map[string]string, changes map[string]string) map[string]string {
+	updated := make(map[string]string)
+	for k, v := range labels {
+		updated[k] = v
+	}
+	for k, v := range changes {
+		if v == "" {
+			delete(updated, k)
+		} else {
+			updated[k] = v
+		}
+	}
+	if len(updated) == 0 {
+		return nil
+	}
+	return updated
+}
+
+// applyConfigOptions changes the settings of c given on the command line.
+func applyConfigOptions(c *state.Config, o *configOptions) {
+	if o.set["interval"] {
+		duration, err := intervals.Parse(o.interval)
 		if err != nil {
-			logger.Fatalf("Cannot parse interval %s: %s", interval, err)
+			logger.Fatalf("Cannot parse interval %s: %s", o.interval, err)
 		}
+		c.Interval = duration
+	}
+	if o.set["suffix"] {
+		c.Suffix = o.suffix
+	}
+	if o.set["collision"] {
+		c.Collision = o.collision
+	}
+	if o.set["utc"] {
+		c.UTC = o.utc
+	}
+	if o.set["naming"] {
+		c.Naming = o.naming
+	}
+	if o.set["retention"] {
+		c.Retention = o.retention
+	}
+	if o.set["archive-dir"] {
+		c.ArchiveDir = o.archiveDir
+	}
+	if o.set["dir-mode"] {
+		c.DirMode = parseMode(o.dirMode)
+	}
+	if o.set["file-mode"] {
+		c.FileMode = parseMode(o.fileMode)
+	}
+	if o.set["owner"] {
+		c.Owner = o.owner
+	}
+	if o.set["group"] {
+		c.Group = o.group
+	}
+	if o.set["label"] {
+		c.Labels = updateLabels(c.Labels, o.labels)
+	}
+}
+
+// createConfig creates the configuration of a log file or updates the given
+// settings of an existing one, keeping its counter and rotation times unless
+// reset is requested; the changed settings are printed.
+func createConfig(file string, o *configOptions) {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		logger.Fatalf("Cannot convert path %s: %s", file, err)
 	}
 	storage := state.MustCreateHomeDirStateStorage()
-	c := defaults.MergeWithDefaultConfig(state.NewConfig(duration, suffix))
-	_, err = state.NewState(storage, absPath, *c)
+	s, err := storage.Load(absPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) && !o.reset {
+		logger.Fatalf("Cannot load configuration for %s: %s", absPath, err)
+	}
+	var old *state.Config
+	var c state.Config
+	if err == nil {
+		old = &s.Config
+	}
+	if err == nil && !o.reset {
+		c = s.Config
+		applyConfigOptions(&c, o)
+		c.Sources = updateSources(c.Sources, o)
+	} else {
+		s = &state.State{FullName: absPath}
+		applyConfigOptions(&c, o)
+		c = *defaults.MergeWithDefaultConfig(&c)
+	}
+	validateConfig(&c)
+	changes := state.Diff(old, &c)
+	s.Config = c
+	err = storage.Store(s)
 	if err != nil {
 		logger.Fatalf("Cannot store configuration: %s", err)
 	}
+	state.WriteChanges(os.Stdout, changes)
+}
+
+func updateSources(sources map[string]string, o *configOptions) map[string]string {
+	updated := make(map[string]string)
+	for k, v := range sources {
+		updated[k] = v
+	}
+	for _, key := range []string{defaults.INTERVAL, defaults.SUFFIX} {
+		if o.set[key] {
+			updated[key] = defaults.SourceFile
+		}
+	}
+	return updated
 }
 
 func collectLogs(file string, tee bool) {
@@ -42,15 +252,29 @@ func collectLogs(file string, tee bool) {
 	if err != nil {
 		logger.Fatalf("Cannot convert path %s: %s", file, err)
 	}
-	lw, err := logwriter.LoadWriter(absPath)
+	lw, err := logwriter.New(absPath)
 	if err != nil {
-		c := defaults.DefaultConfig()
-		lw, err = logwriter.NewWriter(absPath, c)
+		lw, err = logwriter.New(absPath, logwriter.WithConfig(defaults.DefaultConfig()))
 		if err != nil {
 			logger.Fatalf("Cannot create a new writer: %s", err)
 		}
 	}
 	defer lw.Close()
+	rotate := make(chan os.Signal, 1)
+	signal.Notify(rotate, collector.RotateSignal)
+	go func() {
+		for range rotate {
+			if err := lw.Rotate(); err != nil {
+				logger.Printf("Cannot rotate %s: %s", absPath, err)
+			}
+		}
+	}()
+	release, err := collector.Register(absPath)
+	if err != nil {
+		logger.Printf("Cannot register the collector: %s", err)
+	} else {
+		defer release()
+	}
 	var w io.Writer
 	if tee {
 		w = io.MultiWriter(lw, os.Stdout)
@@ -60,21 +284,384 @@ func collectLogs(file string, tee bool) {
 	io.Copy(w, os.Stdin)
 }
 
-func listLogFiles() {
+// requestRotation signals the collector of a log file and waits for the
+// rotation to be recorded in its state.
+func requestRotation(s *state.State, pid int) error {
+	err := collector.RequestRotation(pid)
+	if err != nil {
+		return err
+	}
+	storage := state.MustCreateHomeDirStateStorage()
+	for i := 0; i < 50; i++ {
+		time.Sleep(100 * time.Millisecond)
+		current, err := storage.Load(s.FullName)
+		if err == nil && current.Counter != s.Counter {
+			return nil
+		}
+	}
+	return fmt.Errorf("Collector %d didn't rotate the log file", pid)
+}
+
+func rotateLogFile(s *state.State) error {
+	if !utils.Exists(s.FullName) {
+		logger.Printf("Skipping %s: the log file doesn't exist", s.FullName)
+		return nil
+	}
+	if pid, ok := collector.Find(s.FullName); ok {
+		return requestRotation(s, pid)
+	}
+	lw, err := logwriter.New(s.FullName)
+	if err != nil {
+		return err
+	}
+	err = lw.Rotate()
+	if cerr := lw.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func rotateLogFiles(file string, all bool) {
+	var l []*state.State
+	switch {
+	case all && file != "":
+		logger.Fatal("Cannot use --all with a log file")
+	case all:
+		var err error
+		l, err = state.List()
+		if err != nil {
+			logger.Fatal(err)
+		}
+	case file != "":
+		l = []*state.State{loadState(file)}
+	default:
+		logger.Fatal("A log file or --all is required")
+	}
+	failed := false
+	for _, s := range l {
+		if err := rotateLogFile(s); err != nil {
+			logger.Printf("Cannot rotate %s: %s", s.FullName, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+type listOptions struct {
+	output  string
+	format  string
+	sort    string
+	reverse bool
+	filter  state.Filter
+}
+
+func listLogFiles(o *listOptions) {
 	l, err := state.List()
 	if err != nil {
 		logger.Fatal(err)
 	}
-	err = state.WriteStates(os.Stdout, l)
+	l = o.filter.Apply(l, utils.Exists)
+	err = state.SortStates(l, o.sort, o.reverse)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if o.format != "" {
+		err = state.WriteStatesWithTemplate(os.Stdout, l, o.format)
+	} else {
+		err = state.WriteStatesAs(os.Stdout, l, o.output)
+	}
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func loadState(file string) *state.State {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		logger.Fatalf("Cannot convert path %s: %s", file, err)
+	}
+	s, err := state.MustCreateHomeDirStateStorage().Load(absPath)
+	if err != nil {
+		logger.Fatalf("Cannot find configuration for %s: %s", absPath, err)
+	}
+	return s
+}
+
+func parseTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := intervals.ParseTime(value, time.Now())
+	if err != nil {
+		logger.Fatalf("Cannot parse time %s: %s", value, err)
+	}
+	return t
+}
+
+func catLogs(file string, since string, until string) {
+	s := loadState(file)
+	fs := fsys.NewOSFileSystem()
+	history, err := archives.History(fs, s)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	err = archives.Cat(os.Stdout, fs, archives.Between(history, parseTime(since), parseTime(until)))
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func tailLogs(file string, lines int, follow bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := tail.Tail(ctx, os.Stdout, fsys.NewOSFileSystem(), loadState(file), lines, follow, time.Millisecond*250)
 	if err != nil {
 		logger.Fatal(err)
 	}
 }
 
-func removeLogFile(name string) {
-	err := state.Remove(name)
+func showStatus(file string, output string) {
+	s := loadState(file)
+	pid, _ := collector.Find(s.FullName)
+	st, err := status.Get(fsys.NewOSFileSystem(), s, pid)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if output == state.OutputJSON {
+		err = st.WriteJSON(os.Stdout)
+	} else {
+		err = st.WriteText(os.Stdout, time.Now())
+	}
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+type grepOptions struct {
+	since      string
+	until      string
+	context    int
+	ignoreCase bool
+	search     search.Options
+}
+
+func grepLogs(pattern string, file string, o *grepOptions) {
+	if o.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		logger.Fatalf("Invalid pattern %s: %s", pattern, err)
+	}
+	if o.context > 0 {
+		if o.search.Before == 0 {
+			o.search.Before = o.context
+		}
+		if o.search.After == 0 {
+			o.search.After = o.context
+		}
+	}
+	fs := fsys.NewOSFileSystem()
+	history, err := archives.History(fs, loadState(file))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	matches, err := search.Grep(os.Stdout, fs, archives.Between(history, parseTime(o.since), parseTime(o.until)), re, o.search)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if matches == 0 {
+		os.Exit(1)
+	}
+}
+
+func listArchives(file string) {
+	l, err := archives.List(fsys.NewOSFileSystem(), loadState(file))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	err = archives.WriteArchives(os.Stdout, l)
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	var answer string
+	fmt.Scanln(&answer)
+	return answer == "y" || answer == "Y" || answer == "yes"
+}
+
+func refuseIfCollected(s *state.State) {
+	if pid, ok := collector.Find(s.FullName); ok {
+		logger.Fatalf("%s is collected by process %d", s.FullName, pid)
+	}
+}
+
+type removeOptions struct {
+	purge  bool
+	dryRun bool
+	yes    bool
+}
+
+func simulateRotations(file string, o *configOptions, from string, count int) {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		logger.Fatalf("Cannot convert path %s: %s", file, err)
+	}
+	s, err := state.MustCreateHomeDirStateStorage().Load(absPath)
+	if err != nil {
+		s = &state.State{FullName: absPath, Config: *defaults.DefaultConfig()}
+	}
+	applyConfigOptions(&s.Config, o)
+	validateConfig(&s.Config)
+	start := parseTime(from)
+	if start.IsZero() {
+		start = s.RotatedAt
+	}
+	if start.IsZero() {
+		start = time.Now()
+	}
+	steps, err := simulate.Simulate(fsys.NewOSFileSystem(), s, start, count)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	err = simulate.WriteSteps(os.Stdout, steps)
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func applyManifest(file string, dryRun bool, prune bool) {
+	f, err := os.Open(file)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer f.Close()
+	m, err := manifest.Read(f)
+	if err != nil {
+		logger.Fatalf("Cannot read %s: %s", file, err)
+	}
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		logger.Fatalf("Cannot convert path %s: %s", file, err)
+	}
+	configs, err := m.Configs(filepath.Dir(absPath))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	for name, c := range configs {
+		configs[name] = defaults.MergeWithDefaultConfig(c)
+		validateConfig(configs[name])
+	}
+	storage := state.MustCreateHomeDirStateStorage()
+	states, err := storage.List()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	actions := manifest.Plan(configs, states, prune)
+	err = manifest.WritePlan(os.Stdout, actions)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if dryRun {
+		return
+	}
+	err = manifest.Apply(storage, actions)
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func removeLogFile(name string, o *removeOptions) {
+	if !o.purge {
+		if o.dryRun {
+			fmt.Println(loadState(name).FullName)
+			return
+		}
+		err := state.Remove(name)
+		if err != nil {
+			logger.Fatalf("Cannot remove file %s: %s", name, err)
+		}
+		return
+	}
+	s := loadState(name)
+	refuseIfCollected(s)
+	fs := fsys.NewOSFileSystem()
+	files, err := archives.History(fs, s)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	for _, f := range files {
+		fmt.Println(f.Path)
+	}
+	if o.dryRun {
+		return
+	}
+	if !o.yes && !confirm(fmt.Sprintf("Remove %d files and the configuration of %s?", len(files), s.FullName)) {
+		return
+	}
+	for _, f := range files {
+		if err := fs.Remove(f.Path); err != nil {
+			logger.Fatalf("Cannot remove %s: %s", f.Path, err)
+		}
+	}
+	err = state.Remove(s.FullName)
+	if err != nil {
+		logger.Fatalf("Cannot remove configuration of %s: %s", s.FullName, err)
+	}
+}
+
+// moveLogFile moves a log file and its archives and stores its state under the
+// new name.
+func moveLogFile(from string, to string, dryRun bool) {
+	s := loadState(from)
+	refuseIfCollected(s)
+	if s.Pending != nil {
+		logger.Fatalf("A rotation of %s is pending: collect or rotate it first", s.FullName)
+	}
+	fullName, err := filepath.Abs(to)
+	if err != nil {
+		logger.Fatalf("Cannot convert path %s: %s", to, err)
+	}
+	storage := state.MustCreateHomeDirStateStorage()
+	if _, err := storage.Load(fullName); err == nil {
+		logger.Fatalf("%s is already configured", fullName)
+	}
+	fs := fsys.NewOSFileSystem()
+	renames, err := archives.Renames(fs, s, fullName)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	for _, r := range renames {
+		fmt.Printf("%s -> %s\n", r.From, r.To)
+	}
+	if dryRun {
+		return
+	}
+	dirMode := s.Config.DirMode
+	if dirMode == 0 {
+		dirMode = 0755
+	}
+	err = archives.Move(fs, renames, dirMode)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	oldName := s.FullName
+	s.FullName = fullName
+	err = storage.Store(s)
+	if err != nil {
+		if uerr := archives.Move(fs, archives.Reverse(renames), dirMode); uerr != nil {
+			logger.Printf("Cannot move the files back to %s: %s", oldName, uerr)
+		}
+		logger.Fatalf("Cannot store configuration of %s: %s", fullName, err)
+	}
+	err = storage.Remove(oldName)
 	if err != nil {
-		logger.Fatalf("Cannot remove file %s: %s", name, err)
+		logger.Fatalf("Cannot remove configuration of %s: %s", oldName, err)
 	}
 }
 
@@ -92,6 +679,7 @@ func showOrSetDefaults(interval string, suffix string) {
 		}
 	}
 	c := defaults.MergeWithDefaultConfig(state.NewConfig(duration, suffix))
+	validateConfig(c)
 	err = defaults.SetDefaultConfig(c)
 	if err != nil {
 		logger.Fatalf("Cannot save defaults: %s", err)
@@ -101,27 +689,102 @@ func showOrSetDefaults(interval string, suffix string) {
 func main() {
 	app := kingpin.New("loco", "A log collector")
 	config := app.Command("config", "Configures a log file")
-	configInterval := config.Flag("interval", "Rotate interval").Short('i').String()
-	configSuffix := config.Flag("suffix", "Rotated file suffix").Short('s').String()
+	configOpts := &configOptions{labels: make(map[string]string), set: make(map[string]bool)}
+	configFlags(config, configOpts)
+	config.Flag("reset", "Replace the configuration and reset the counter and the rotation times").BoolVar(&configOpts.reset)
 	configFile := config.Arg("file", "Log file").Required().String()
 	collect := app.Command("collect", "Collects stdin and redirects to a log file")
 	collectTee := collect.Flag("tee", "Write to log file and stdout").Short('t').Bool()
 	collectFile := collect.Arg("file", "Log file").Required().String()
 	list := app.Command("list", "Lists the registered log files")
+	listOpts := &listOptions{filter: state.Filter{Labels: make(map[string]string)}}
+	list.Flag("output", "Output format").
+		Short('o').
+		Default(state.OutputTable).
+		EnumVar(&listOpts.output, state.OutputTable, state.OutputJSON, state.OutputCSV, state.OutputYAML)
+	list.Flag("format", "Go text/template executed for each log file, e.g. '{{.FullName}}'").StringVar(&listOpts.format)
+	list.Flag("sort", "Sort key").Default("name").EnumVar(&listOpts.sort, state.SortKeys()...)
+	list.Flag("reverse", "Reverse the order").Short('r').BoolVar(&listOpts.reverse)
+	list.Flag("path-prefix", "Only list log files under this path").StringVar(&listOpts.filter.PathPrefix)
+	list.Flag("stale", "Only list log files that no longer exist").BoolVar(&listOpts.filter.Stale)
+	list.Flag("label", "Only list log files with this label (key=value)").StringMapVar(&listOpts.filter.Labels)
+	statusCmd := app.Command("status", "Shows the configuration, the next rotation and the statistics of a log file")
+	statusOutput := statusCmd.Flag("output", "Output format").Short('o').Default("text").Enum("text", state.OutputJSON)
+	statusFile := statusCmd.Arg("file", "Log file").Required().String()
+	files := app.Command("files", "Lists the rotated files of a log file, from the oldest")
+	filesFile := files.Arg("file", "Log file").Required().String()
+	cat := app.Command("cat", "Prints the rotated files and the log file, from the oldest")
+	catSince := cat.Flag("since", "Skip files written before this time (e.g. 2018-12-09 or 2h)").String()
+	catUntil := cat.Flag("until", "Skip files written after this time").String()
+	catFile := cat.Arg("file", "Log file").Required().String()
+	tailCmd := app.Command("tail", "Prints the last lines of a log file, reading the rotated files if needed")
+	tailLines := tailCmd.Flag("lines", "Number of lines").Short('n').Default("10").Int()
+	tailFollow := tailCmd.Flag("follow", "Keep printing the lines appended to the log file, across rotations").Short('f').Bool()
+	tailFile := tailCmd.Arg("file", "Log file").Required().String()
+	grep := app.Command("grep", "Searches the rotated files and the log file")
+	grepOpts := &grepOptions{}
+	grep.Flag("since", "Skip files written before this time (e.g. 2018-12-09 or 2h)").StringVar(&grepOpts.since)
+	grep.Flag("until", "Skip files written after this time").StringVar(&grepOpts.until)
+	grep.Flag("after-context", "Lines printed after each match").Short('A').IntVar(&grepOpts.search.After)
+	grep.Flag("before-context", "Lines printed before each match").Short('B').IntVar(&grepOpts.search.Before)
+	grep.Flag("context", "Lines printed before and after each match").Short('C').IntVar(&grepOpts.context)
+	grep.Flag("ignore-case", "Ignore case").Short('i').BoolVar(&grepOpts.ignoreCase)
+	grep.Flag("line-number", "Print line numbers").Short('n').BoolVar(&grepOpts.search.LineNumbers)
+	grepPattern := grep.Arg("pattern", "Regular expression").Required().String()
+	grepFile := grep.Arg("file", "Log file").Required().String()
+	simulateCmd := app.Command("simulate", "Shows the next rotations of a log file, with the given settings, without touching it")
+	simulateOpts := &configOptions{labels: make(map[string]string), set: make(map[string]bool)}
+	configFlags(simulateCmd, simulateOpts)
+	simulateFrom := simulateCmd.Flag("from", "Start of the simulation (default: the last rotation)").String()
+	simulateCount := simulateCmd.Flag("count", "Number of rotations").Default("10").Int()
+	simulateFile := simulateCmd.Arg("file", "Log file").Required().String()
+	apply := app.Command("apply", "Configures the log files listed in a YAML manifest")
+	applyFile := apply.Flag("file", "Manifest").Short('f').Required().String()
+	applyDryRun := apply.Flag("dry-run", "Only print the changes").Bool()
+	applyPrune := apply.Flag("prune", "Remove the configurations of log files missing from the manifest").Bool()
+	rotate := app.Command("rotate", "Rotates a log file now, through its collector if one is attached")
+	rotateAll := rotate.Flag("all", "Rotate every configured log file").Bool()
+	rotateFile := rotate.Arg("file", "Log file").String()
 	remove := app.Command("remove", "Removes a log file")
+	removeOpts := &removeOptions{}
+	remove.Flag("purge", "Also delete the log file and its rotated files").BoolVar(&removeOpts.purge)
+	remove.Flag("dry-run", "Only print the configuration and the files that would be deleted").BoolVar(&removeOpts.dryRun)
+	remove.Flag("yes", "Don't ask for confirmation").Short('y').BoolVar(&removeOpts.yes)
 	removeFile := remove.Arg("file", "Log file").Required().String()
+	mv := app.Command("mv", "Moves a log file and its rotated files")
+	mvDryRun := mv.Flag("dry-run", "Only print the files that would be moved").Bool()
+	mvFrom := mv.Arg("from", "Log file").Required().String()
+	mvTo := mv.Arg("to", "New name of the log file").Required().String()
 	defaults := app.Command("defaults", "Shows or sets default options")
 	defaultsInterval := defaults.Flag("interval", "Rotate interval").Short('i').String()
 	defaultsSuffix := defaults.Flag("suffix", "Rotated file suffix").Short('s').String()
 	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
 	case config.FullCommand():
-		createConfig(*configFile, *configInterval, *configSuffix)
+		createConfig(*configFile, configOpts)
 	case collect.FullCommand():
 		collectLogs(*collectFile, *collectTee)
 	case list.FullCommand():
-		listLogFiles()
+		listLogFiles(listOpts)
+	case statusCmd.FullCommand():
+		showStatus(*statusFile, *statusOutput)
+	case files.FullCommand():
+		listArchives(*filesFile)
+	case cat.FullCommand():
+		catLogs(*catFile, *catSince, *catUntil)
+	case tailCmd.FullCommand():
+		tailLogs(*tailFile, *tailLines, *tailFollow)
+	case grep.FullCommand():
+		grepLogs(*grepPattern, *grepFile, grepOpts)
+	case simulateCmd.FullCommand():
+		simulateRotations(*simulateFile, simulateOpts, *simulateFrom, *simulateCount)
+	case apply.FullCommand():
+		applyManifest(*applyFile, *applyDryRun, *applyPrune)
+	case rotate.FullCommand():
+		rotateLogFiles(*rotateFile, *rotateAll)
 	case remove.FullCommand():
-		removeLogFile(*removeFile)
+		removeLogFile(*removeFile, removeOpts)
+	case mv.FullCommand():
+		moveLogFile(*mvFrom, *mvTo, *mvDryRun)
 	case defaults.FullCommand():
 		showOrSetDefaults(*defaultsInterval, *defaultsSuffix)
 	}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// A collector registers itself writing its pid to a file named after the log
+// file, so that other loco commands can find it and signal it.
+
+func pidFile(dir string, fullName string) string {
+	return path.Join(dir, fmt.Sprintf("%s.pid", utils.MD5(fullName)))
+}
+
+// RegisterIn records that the current process collects fullName; the returned
+// function removes the registration.
+func RegisterIn(dir string, fullName string) (func(), error) {
+	err := utils.CreateDirIfNotExists(dir)
+	if err != nil {
+		return nil, err
+	}
+	name := pidFile(dir, fullName)
+	if pid, ok := FindIn(dir, fullName); ok && pid != os.Getpid() {
+		return nil, fmt.Errorf("%s is already collected by process %d", fullName, pid)
+	}
+	err = ioutil.WriteFile(name, []byte(strconv.Itoa(os.Getpid())), 0644)
+	if err != nil {
+		return nil, utils.Wrapf(err, "Cannot write %s", name)
+	}
+	return func() { os.Remove(name) }, nil
+}
+
+// FindIn returns the pid of the process collecting fullName, if it's running.
+func FindIn(dir string, fullName string) (int, bool) {
+	b, err := ioutil.ReadFile(pidFile(dir, fullName))
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 || !alive(pid) {
+		return 0, false
+	}
+	return pid, true
+}
+
+func runDir() (string, error) {
+	appDir, err := utils.AppDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(appDir, "run"), nil
+}
+
+func Register(fullName string) (func(), error) {
+	dir, err := runDir()
+	if err != nil {
+		return nil, err
+	}
+	return RegisterIn(dir, fullName)
+}
+
+func Find(fullName string) (int, bool) {
+	dir, err := runDir()
+	if err != nil {
+		return 0, false
+	}
+	return FindIn(dir, fullName)
+}
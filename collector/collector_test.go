@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterAndFind(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	_, ok := FindIn(dir, "/logs/app.log")
+	assert.False(t, ok)
+	release, err := RegisterIn(dir, "/logs/app.log")
+	assert.NoError(t, err)
+	pid, ok := FindIn(dir, "/logs/app.log")
+	assert.True(t, ok)
+	assert.Equal(t, os.Getpid(), pid)
+	release()
+	_, ok = FindIn(dir, "/logs/app.log")
+	assert.False(t, ok)
+}
+
+func TestFindIgnoresDeadProcesses(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(pidFile(dir, "/logs/app.log"), []byte("999999999"), 0644)
+	_, ok := FindIn(dir, "/logs/app.log")
+	assert.False(t, ok)
+	ioutil.WriteFile(pidFile(dir, "/logs/app.log"), []byte("garbage"), 0644)
+	_, ok = FindIn(dir, "/logs/app.log")
+	assert.False(t, ok)
+	_, err := RegisterIn(dir, "/logs/app.log")
+	assert.NoError(t, err)
+}
+
+func TestRegisterFailsIfAnotherProcessCollects(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(pidFile(dir, "/logs/app.log"), []byte("1"), 0644)
+	_, err := RegisterIn(dir, "/logs/app.log")
+	assert.Error(t, err)
+}
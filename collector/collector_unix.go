@@ -0,0 +1,17 @@
+//go:build !windows
+
+package collector
+
+import "syscall"
+
+func alive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// RotateSignal is the signal that asks a collector to rotate its log file.
+const RotateSignal = syscall.SIGUSR1
+
+func RequestRotation(pid int) error {
+	return syscall.Kill(pid, RotateSignal)
+}
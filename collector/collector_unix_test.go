@@ -0,0 +1,25 @@
+//go:build !windows
+
+package collector
+
+import (
+	"os"
+	"os/signal"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestRotation(t *testing.T) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, RotateSignal)
+	defer signal.Stop(c)
+	assert.NoError(t, RequestRotation(os.Getpid()))
+	select {
+	case s := <-c:
+		assert.Equal(t, RotateSignal, s)
+	case <-time.After(time.Second):
+		t.Fatal("signal not received")
+	}
+}
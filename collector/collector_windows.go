@@ -0,0 +1,22 @@
+//go:build windows
+
+package collector
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func alive(pid int) bool {
+	_, err := os.FindProcess(pid)
+	return err == nil
+}
+
+// RotateSignal is never delivered on Windows, where collectors can't be
+// signalled.
+const RotateSignal = syscall.Signal(0x1e)
+
+func RequestRotation(pid int) error {
+	return errors.New("Rotation requests are not supported on this platform")
+}
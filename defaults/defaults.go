@@ -167,17 +167,51 @@ func (r *compositeConfigReader) GetInt(key string) (int64, error) {
 	return 0, fmt.Errorf("Cannot read a value for %s", key)
 }
 
+// Sources of the values of a configuration: the configuration of the log
+// file, the environment, the defaults file or the built-in defaults.
+const (
+	SourceFile     = "file"
+	SourceEnv      = "env"
+	SourceDefaults = "defaults"
+	SourceBuiltin  = "builtin"
+)
+
 var homeDirConfigReader = &jsonFileConfigReader{path.Join(utils.MustGetAppDir(), "defaults.json")}
 
+var envDefaultsReader = &envConfigReader{
+	keys:      map[string]string{INTERVAL: "LOCO_INTERVAL", SUFFIX: "LOCO_SUFFIX"},
+	envReader: &defaultEnvReader{},
+}
+
+var builtinDefaultsReader = &mapConfigReader{map[string]interface{}{INTERVAL: int64(time.Hour * 24), SUFFIX: "%c"}}
+
 func newConfigReader() ConfigReader {
-	return &compositeConfigReader{[]ConfigReader{
-		&envConfigReader{
-			keys:      map[string]string{INTERVAL: "LOCO_INTERVAL", SUFFIX: "LOCO_SUFFIX"},
-			envReader: &defaultEnvReader{},
-		},
-		homeDirConfigReader,
-		&mapConfigReader{map[string]interface{}{INTERVAL: int64(time.Hour * 24), SUFFIX: "%c"}},
-	}}
+	return &compositeConfigReader{[]ConfigReader{envDefaultsReader, homeDirConfigReader, builtinDefaultsReader}}
+}
+
+// sources returns, for each key, the name of the first reader having a value.
+func sources(readers []ConfigReader, names []string) map[string]string {
+	s := make(map[string]string)
+	for i, r := range readers {
+		if _, ok := s[INTERVAL]; !ok {
+			if _, err := r.GetInt(INTERVAL); err == nil {
+				s[INTERVAL] = names[i]
+			}
+		}
+		if _, ok := s[SUFFIX]; !ok {
+			if _, err := r.GetString(SUFFIX); err == nil {
+				s[SUFFIX] = names[i]
+			}
+		}
+	}
+	return s
+}
+
+func DefaultSources() map[string]string {
+	return sources(
+		[]ConfigReader{envDefaultsReader, homeDirConfigReader, builtinDefaultsReader},
+		[]string{SourceEnv, SourceDefaults, SourceBuiltin},
+	)
 }
 
 func mustGetString(cr ConfigReader, key string) string {
@@ -199,11 +233,12 @@ func mustGetInt(cr ConfigReader, key string) int64 {
 var configReader = newConfigReader()
 
 func DefaultConfig() *state.Config {
-	return state.NewConfig(
+	c := state.NewConfig(
 		time.Duration(mustGetInt(configReader, INTERVAL)),
 		mustGetString(configReader, SUFFIX),
 	)
-
+	c.Sources = DefaultSources()
+	return c
 }
 func SetDefaultConfig(c *state.Config) error {
 	return homeDirConfigReader.writeDefaults(
@@ -212,19 +247,33 @@ func SetDefaultConfig(c *state.Config) error {
 }
 
 func mergeWithDefaultConfig(c *state.Config, d *state.Config) *state.Config {
-	interval := d.Interval
-	suffix := d.Suffix
-	if c.Interval != time.Duration(0) {
-		interval = c.Interval
+	merged := *c
+	if c.Interval == time.Duration(0) {
+		merged.Interval = d.Interval
 	}
-	if c.Suffix != "" {
-		suffix = c.Suffix
+	if c.Suffix == "" {
+		merged.Suffix = d.Suffix
 	}
-	return state.NewConfig(interval, suffix)
+	return &merged
+}
+
+// mergeSources returns the sources of the values of c merged with d.
+func mergeSources(c *state.Config, d *state.Config) map[string]string {
+	s := map[string]string{INTERVAL: SourceFile, SUFFIX: SourceFile}
+	if c.Interval == time.Duration(0) {
+		s[INTERVAL] = d.Sources[INTERVAL]
+	}
+	if c.Suffix == "" {
+		s[SUFFIX] = d.Sources[SUFFIX]
+	}
+	return s
 }
 
 func MergeWithDefaultConfig(c *state.Config) *state.Config {
-	return mergeWithDefaultConfig(c, DefaultConfig())
+	d := DefaultConfig()
+	merged := mergeWithDefaultConfig(c, d)
+	merged.Sources = mergeSources(c, d)
+	return merged
 }
 
 func writeDefaultConfig(w io.Writer, c *state.Config) {
@@ -288,6 +288,11 @@ func TestMergeWithDefaultConfig(t *testing.T) {
 	assert.Equal(t, state.NewConfig(time.Hour*2, "bar"), mergeWithDefaultConfig(state.NewConfig(0, "bar"), d))
 	assert.Equal(t, state.NewConfig(time.Hour*4, "foo"), mergeWithDefaultConfig(state.NewConfig(time.Hour*4, ""), d))
 	assert.Equal(t, d, mergeWithDefaultConfig(state.NewConfig(0, ""), d))
+	assert.Equal(
+		t,
+		&state.Config{Interval: time.Hour * 2, Suffix: "foo", Collision: state.CollisionFail},
+		mergeWithDefaultConfig(&state.Config{Collision: state.CollisionFail}, d),
+	)
 }
 
 func TestWriteDefaultConfig(t *testing.T) {
@@ -296,3 +301,24 @@ func TestWriteDefaultConfig(t *testing.T) {
 	writeDefaultConfig(&buf, c)
 	assert.Equal(t, "Interval: 3h0m0s\nSuffix:   foo\n", buf.String())
 }
+
+func TestSources(t *testing.T) {
+	env := &envConfigReader{
+		keys:      map[string]string{INTERVAL: "LOCO_INTERVAL", SUFFIX: "LOCO_SUFFIX"},
+		envReader: &testEnvReader{map[string]string{"LOCO_SUFFIX": "%Y"}},
+	}
+	file := &mapConfigReader{map[string]interface{}{INTERVAL: int64(time.Hour)}}
+	builtin := &mapConfigReader{map[string]interface{}{INTERVAL: int64(time.Hour * 24), SUFFIX: "%c"}}
+	assert.Equal(
+		t,
+		map[string]string{INTERVAL: SourceDefaults, SUFFIX: SourceEnv},
+		sources([]ConfigReader{env, file, builtin}, []string{SourceEnv, SourceDefaults, SourceBuiltin}),
+	)
+}
+
+func TestMergeSources(t *testing.T) {
+	d := state.NewConfig(time.Hour*2, "foo")
+	d.Sources = map[string]string{INTERVAL: SourceEnv, SUFFIX: SourceBuiltin}
+	assert.Equal(t, map[string]string{INTERVAL: SourceEnv, SUFFIX: SourceFile}, mergeSources(state.NewConfig(0, "bar"), d))
+	assert.Equal(t, map[string]string{INTERVAL: SourceFile, SUFFIX: SourceBuiltin}, mergeSources(state.NewConfig(time.Hour, ""), d))
+}
@@ -2,9 +2,10 @@ package filename
 
 import (
 	"fmt"
+	"os"
 	"path"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lorenzobenvenuti/loco/state"
 )
@@ -24,41 +25,104 @@ func splitBaseNameAndExtension(file string) (string, string) {
 	return basename, ext
 }
 
-type patternTranslator func(s *state.State) string
-
 type suffixFileNameGenerator struct {
-	patternTranslators map[string]patternTranslator
+	local    *time.Location
+	hostname func() (string, error)
+	pid      func() int
 }
 
-func (m *suffixFileNameGenerator) add(pattern string, t patternTranslator) {
-	m.patternTranslators[pattern] = t
+func (m *suffixFileNameGenerator) context(s *state.State) *renderContext {
+	t := s.RotatedAt.In(m.local)
+	if s.Config.UTC {
+		t = s.RotatedAt.UTC()
+	}
+	hostname, err := m.hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return &renderContext{state: s, time: t, hostname: hostname, pid: m.pid()}
 }
 
-func (m *suffixFileNameGenerator) suffixFromState(state *state.State) string {
-	tokens := strings.Split(state.Config.Suffix, "%%")
-	for i, _ := range tokens {
-		for k, v := range m.patternTranslators {
-			tokens[i] = strings.Replace(tokens[i], k, v(state), -1)
+func (m *suffixFileNameGenerator) render(template string, s *state.State) string {
+	tokens, _ := tokenize(template)
+	c := m.context(s)
+	var b strings.Builder
+	for _, t := range tokens {
+		if t.isLiteral() {
+			b.WriteString(t.literal)
+		} else {
+			b.WriteString(tokenTable[t.verb].translate(c, t.width))
 		}
 	}
-	return strings.Join(tokens, "%")
+	return b.String()
+}
+
+func (m *suffixFileNameGenerator) suffixFromState(s *state.State) string {
+	return m.render(s.Config.Suffix, s)
+}
+
+// archiveDir renders the archive directory of the configuration, resolving
+// relative paths against the directory of the log file; archives are kept
+// next to the log file if no directory is configured.
+func (m *suffixFileNameGenerator) archiveDir(s *state.State) string {
+	dir := path.Dir(s.FullName)
+	if s.Config.ArchiveDir == "" {
+		return dir
+	}
+	rendered := m.render(s.Config.ArchiveDir, s)
+	if path.IsAbs(rendered) {
+		return path.Clean(rendered)
+	}
+	return path.Join(dir, rendered)
 }
 
 func (m *suffixFileNameGenerator) FileName(state *state.State) string {
-	dir, file := path.Split(state.FullName)
+	_, file := path.Split(state.FullName)
 	basename, ext := splitBaseNameAndExtension(file)
 	suffix := m.suffixFromState(state)
-	return path.Join(dir, fmt.Sprintf("%s.%s%s", basename, suffix, ext))
+	return path.Join(m.archiveDir(state), fmt.Sprintf("%s.%s%s", basename, suffix, ext))
+}
+
+// ArchiveDir returns the directory where g archives the log file of the state.
+func ArchiveDir(g FileNameGenerator, s *state.State) string {
+	if d, ok := g.(interface{ archiveDir(*state.State) string }); ok {
+		return d.archiveDir(s)
+	}
+	return path.Dir(g.FileName(s))
+}
+
+// ShiftedFileName returns the name of the i-th archive when archives are
+// shifted, as logrotate does without dateext: file.log.1 is the newest.
+func ShiftedFileName(fullName string, i int) string {
+	return fmt.Sprintf("%s.%d", fullName, i)
+}
+
+// Uniquify returns the first name that doesn't exist among name, name-1,
+// name-2 and so on; the counter is added before the extension.
+func Uniquify(name string, exists func(string) bool) string {
+	if !exists(name) {
+		return name
+	}
+	basename, ext := splitBaseNameAndExtension(name)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", basename, i, ext)
+		if !exists(candidate) {
+			return candidate
+		}
+	}
+}
+
+func newFileNameGenerator(local *time.Location) *suffixFileNameGenerator {
+	return &suffixFileNameGenerator{
+		local:    local,
+		hostname: os.Hostname,
+		pid:      os.Getpid,
+	}
 }
 
+// NewFileNameGenerator returns a generator that adds the configured suffix
+// to the file name; time tokens are rendered in local time unless the
+// configuration requires UTC.
 func NewFileNameGenerator() FileNameGenerator {
-	generator := &suffixFileNameGenerator{make(map[string]patternTranslator)}
-	generator.add("%c", func(s *state.State) string { return strconv.Itoa(s.Counter) })
-	generator.add("%Y", func(s *state.State) string { return strconv.Itoa(s.RotatedAt.Year()) })
-	generator.add("%m", func(s *state.State) string { return fmt.Sprintf("%02d", int(s.RotatedAt.Month())) })
-	generator.add("%d", func(s *state.State) string { return fmt.Sprintf("%02d", s.RotatedAt.Day()) })
-	generator.add("%H", func(s *state.State) string { return fmt.Sprintf("%02d", s.RotatedAt.Hour()) })
-	generator.add("%M", func(s *state.State) string { return fmt.Sprintf("%02d", s.RotatedAt.Minute()) })
-	generator.add("%S", func(s *state.State) string { return fmt.Sprintf("%02d", s.RotatedAt.Second()) })
-	return generator
+	return newFileNameGenerator(time.Local)
 }
@@ -9,7 +9,7 @@ import (
 )
 
 func TestSuffixWithExtension(t *testing.T) {
-	sut := NewFileNameGenerator()
+	sut := newFileNameGenerator(time.UTC)
 	state := &state.State{
 		FullName: "/path/to/file.log",
 		Counter:  1,
@@ -19,7 +19,7 @@ func TestSuffixWithExtension(t *testing.T) {
 }
 
 func TestSuffixWithoutExtension(t *testing.T) {
-	sut := NewFileNameGenerator()
+	sut := newFileNameGenerator(time.UTC)
 	state := &state.State{
 		FullName: "/path/to/file",
 		Counter:  1,
@@ -34,7 +34,7 @@ func TestSuffixWithCounter(t *testing.T) {
 		Counter:  1,
 		Config:   state.Config{Suffix: "X%cY"},
 	}
-	sut := NewFileNameGenerator()
+	sut := newFileNameGenerator(time.UTC)
 	assert.Equal(t, "/path/to/file.X1Y.log", sut.FileName(state))
 }
 
@@ -44,7 +44,7 @@ func TestSuffixWithTrailingCounter(t *testing.T) {
 		Counter:  1,
 		Config:   state.Config{Suffix: "%cX"},
 	}
-	sut := NewFileNameGenerator()
+	sut := newFileNameGenerator(time.UTC)
 	assert.Equal(t, "/path/to/file.1X.log", sut.FileName(state))
 }
 
@@ -54,7 +54,7 @@ func TestEscapedPercent(t *testing.T) {
 		Counter:  1,
 		Config:   state.Config{Suffix: "c%%%cc"},
 	}
-	sut := NewFileNameGenerator()
+	sut := newFileNameGenerator(time.UTC)
 	assert.Equal(t, "/path/to/file.c%1c.log", sut.FileName(state))
 }
 
@@ -64,7 +64,7 @@ func TestTwoEscapedPercent(t *testing.T) {
 		Counter:  1,
 		Config:   state.Config{Suffix: "c%%%%%cc"},
 	}
-	sut := NewFileNameGenerator()
+	sut := newFileNameGenerator(time.UTC)
 	assert.Equal(t, "/path/to/file.c%%1c.log", sut.FileName(state))
 }
 
@@ -76,7 +76,7 @@ func TestSuffixWithDate(t *testing.T) {
 		Config:    state.Config{Suffix: "X%Y%m%dY"},
 		RotatedAt: now,
 	}
-	sut := NewFileNameGenerator()
+	sut := newFileNameGenerator(time.UTC)
 	assert.Equal(t, "/path/to/file.X20181209Y.log", sut.FileName(state))
 }
 
@@ -88,6 +88,109 @@ func TestSuffixWithDateAndTime(t *testing.T) {
 		Config:    state.Config{Suffix: "X%Y%m%d%H%M%SY"},
 		RotatedAt: now,
 	}
-	sut := NewFileNameGenerator()
+	sut := newFileNameGenerator(time.UTC)
 	assert.Equal(t, "/path/to/file.X20181209152132Y.log", sut.FileName(state))
 }
+
+func TestUniquify(t *testing.T) {
+	existing := map[string]bool{
+		"/path/to/file.1.log":   true,
+		"/path/to/file.1-1.log": true,
+		"/path/to/file":         true,
+	}
+	exists := func(name string) bool { return existing[name] }
+	assert.Equal(t, "/path/to/file.2.log", Uniquify("/path/to/file.2.log", exists))
+	assert.Equal(t, "/path/to/file.1-2.log", Uniquify("/path/to/file.1.log", exists))
+	assert.Equal(t, "/path/to/file-1", Uniquify("/path/to/file", exists))
+}
+
+func newTestGenerator() *suffixFileNameGenerator {
+	g := newFileNameGenerator(time.FixedZone("CET", 3600))
+	g.hostname = func() (string, error) { return "myhost", nil }
+	g.pid = func() int { return 4242 }
+	return g
+}
+
+func TestSuffixWithRicherTokens(t *testing.T) {
+	now := time.Date(2018, 12, 31, 23, 30, 5, 0, time.UTC)
+	sut := newTestGenerator()
+	for suffix, expected := range map[string]string{
+		"%h-%p":    "myhost-4242",
+		"%s":       "1546299005",
+		"%G-W%V":   "2019-W01",
+		"%Y%m%d":   "20190101",
+		"%j":       "001",
+		"%b":       "Jan",
+		"%4c":      "0007",
+		"%c":       "7",
+		"%1c":      "7",
+		"x%%%3c%%": "x%007%",
+	} {
+		s := &state.State{
+			FullName:  "/path/to/file.log",
+			Counter:   7,
+			RotatedAt: now,
+			Config:    state.Config{Suffix: suffix},
+		}
+		assert.Equal(t, "/path/to/file."+expected+".log", sut.FileName(s), suffix)
+	}
+}
+
+func TestSuffixInUTC(t *testing.T) {
+	s := &state.State{
+		FullName:  "/path/to/file.log",
+		RotatedAt: time.Date(2018, 12, 31, 23, 30, 5, 0, time.UTC),
+		Config:    state.Config{Suffix: "%Y%m%d%H", UTC: true},
+	}
+	assert.Equal(t, "/path/to/file.2018123123.log", newTestGenerator().FileName(s))
+}
+
+func TestSuffixWithUnknownTokens(t *testing.T) {
+	s := &state.State{
+		FullName: "/path/to/file.log",
+		Counter:  1,
+		Config:   state.Config{Suffix: "%q%c%2Y%"},
+	}
+	assert.Equal(t, "/path/to/file.%q1%2Y%.log", newTestGenerator().FileName(s))
+}
+
+func TestShiftedFileName(t *testing.T) {
+	assert.Equal(t, "/logs/file.log.3", ShiftedFileName("/logs/file.log", 3))
+}
+
+func TestArchiveDir(t *testing.T) {
+	s := &state.State{
+		FullName:  "/path/to/file.log",
+		Counter:   3,
+		RotatedAt: time.Date(2018, 12, 9, 0, 0, 0, 0, time.UTC),
+		Config:    state.Config{Suffix: "%c", UTC: true},
+	}
+	sut := newTestGenerator()
+	for dir, expected := range map[string]string{
+		"":                    "/path/to/file.3.log",
+		"/var/log/app/%Y/%m/": "/var/log/app/2018/12/file.3.log",
+		"archive/%h":          "/path/to/archive/myhost/file.3.log",
+		"../archive":          "/path/archive/file.3.log",
+	} {
+		s.Config.ArchiveDir = dir
+		assert.Equal(t, expected, sut.FileName(s), dir)
+	}
+}
+
+type fixedGenerator string
+
+func (g fixedGenerator) FileName(s *state.State) string {
+	return string(g)
+}
+
+func TestArchiveDirUsesTheGenerator(t *testing.T) {
+	s := &state.State{
+		FullName:  "/path/to/file.log",
+		RotatedAt: time.Date(2018, 12, 31, 23, 0, 0, 0, time.UTC),
+		Config:    state.Config{ArchiveDir: "/archive/%Y"},
+	}
+	assert.Equal(t, "/archive/2019", ArchiveDir(newFileNameGenerator(time.FixedZone("CET", 3600)), s))
+	s.Config.UTC = true
+	assert.Equal(t, "/archive/2018", ArchiveDir(newFileNameGenerator(time.FixedZone("CET", 3600)), s))
+	assert.Equal(t, "/other", ArchiveDir(fixedGenerator("/other/file.1.log"), s))
+}
@@ -0,0 +1,225 @@
+package filename
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/state"
+)
+
+var compressions = map[string]string{
+	".gz":  "gzip",
+	".bz2": "bzip2",
+	".zst": "zstd",
+}
+
+var tokenPatterns = map[byte]string{
+	'Y': `\d{4}`,
+	'm': `\d{2}`,
+	'd': `\d{2}`,
+	'H': `\d{2}`,
+	'M': `\d{2}`,
+	'S': `\d{2}`,
+	's': `\d+`,
+	'j': `\d{3}`,
+	'b': `Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec`,
+	'V': `\d{2}`,
+	'G': `\d{4}`,
+	'h': `[^/]+?`,
+	'p': `\d+`,
+}
+
+// Match holds what can be recovered from the name of an archive. Time is zero
+// if the templates have no time tokens and Counter is -1 if they have no %c;
+// Index is the uniquifier added on collisions or, for shifted archives, the
+// position of the archive (1 is the newest). Host is the value of %h, if any.
+type Match struct {
+	Time        time.Time
+	Counter     int
+	Index       int
+	Host        string
+	Compression string
+}
+
+// Parser inverts the suffix and the archive directory of a configuration,
+// matching the names of its archives.
+type Parser struct {
+	re    *regexp.Regexp
+	verbs []byte
+	root  string
+	depth int
+	shift bool
+	loc   *time.Location
+}
+
+func tokensPattern(template string) (string, []byte, error) {
+	tokens, err := tokenize(template)
+	if err != nil {
+		return "", nil, err
+	}
+	var b strings.Builder
+	verbs := make([]byte, 0)
+	for _, t := range tokens {
+		switch {
+		case t.isLiteral():
+			b.WriteString(regexp.QuoteMeta(t.literal))
+		case t.verb == 'c' && t.width > 0:
+			b.WriteString(fmt.Sprintf(`(\d{%d,})`, t.width))
+			verbs = append(verbs, t.verb)
+		case t.verb == 'c':
+			b.WriteString(`(\d+)`)
+			verbs = append(verbs, t.verb)
+		default:
+			b.WriteString("(" + tokenPatterns[t.verb] + ")")
+			verbs = append(verbs, t.verb)
+		}
+	}
+	return b.String(), verbs, nil
+}
+
+// staticRoot returns the longest leading part of dir without tokens and the
+// number of path elements that follow it.
+func staticRoot(dir string) (string, int) {
+	elems := strings.Split(dir, "/")
+	for i, e := range elems {
+		if strings.Contains(e, "%") {
+			return path.Clean("/" + strings.Join(elems[:i], "/")), len(elems) - i
+		}
+	}
+	return dir, 0
+}
+
+func newParser(s *state.State, loc *time.Location) (*Parser, error) {
+	if s.Config.UTC {
+		loc = time.UTC
+	}
+	logDir, file := path.Split(s.FullName)
+	dir := path.Clean(logDir)
+	if s.Config.ArchiveDir != "" {
+		dir = s.Config.ArchiveDir
+		if !path.IsAbs(dir) {
+			dir = path.Join(logDir, dir)
+		}
+		dir = path.Clean(dir)
+	}
+	p := &Parser{loc: loc, shift: s.Config.Naming == state.NamingShift}
+	p.root, p.depth = staticRoot(dir)
+	dirPattern, verbs, err := tokensPattern(dir)
+	if err != nil {
+		return nil, err
+	}
+	var filePattern string
+	if p.shift {
+		filePattern = regexp.QuoteMeta(file) + `\.(\d+)`
+		verbs = append(verbs, 'i')
+	} else {
+		basename, ext := splitBaseNameAndExtension(file)
+		suffixPattern, suffixVerbs, err := tokensPattern(s.Config.Suffix)
+		if err != nil {
+			return nil, err
+		}
+		filePattern = regexp.QuoteMeta(basename+".") + suffixPattern + `(?:-(\d+))?` + regexp.QuoteMeta(ext)
+		verbs = append(append(verbs, suffixVerbs...), 'i')
+	}
+	re, err := regexp.Compile("^" + strings.TrimSuffix(dirPattern, "/") + "/" + filePattern + `(\.gz|\.bz2|\.zst)?$`)
+	if err != nil {
+		return nil, err
+	}
+	p.re = re
+	p.verbs = verbs
+	return p, nil
+}
+
+// NewParser returns a parser for the archives of a state; time tokens are
+// read in local time unless the configuration requires UTC.
+func NewParser(s *state.State) (*Parser, error) {
+	return newParser(s, time.Local)
+}
+
+// Root is the directory holding the archives and Depth is how many levels of
+// subdirectories of Root are part of their names.
+func (p *Parser) Root() string {
+	return p.root
+}
+
+func (p *Parser) Depth() int {
+	return p.depth
+}
+
+// Shifted tells whether the archives are shifted, i.e. Match.Index is their
+// position.
+func (p *Parser) Shifted() bool {
+	return p.shift
+}
+
+func (p *Parser) Parse(name string) (*Match, bool) {
+	groups := p.re.FindStringSubmatch(path.Clean(name))
+	if groups == nil {
+		return nil, false
+	}
+	m := &Match{Counter: -1, Compression: compressions[groups[len(groups)-1]]}
+	values := make(map[byte]string)
+	for i, verb := range p.verbs {
+		values[verb] = groups[i+1]
+	}
+	if v, ok := values['c']; ok {
+		m.Counter, _ = strconv.Atoi(v)
+	}
+	if v := values['i']; v != "" {
+		m.Index, _ = strconv.Atoi(v)
+	}
+	m.Host = values['h']
+	m.Time = p.time(values)
+	return m, true
+}
+
+func (p *Parser) time(values map[byte]string) time.Time {
+	number := func(verb byte, def int) int {
+		if v, ok := values[verb]; ok {
+			n, _ := strconv.Atoi(v)
+			return n
+		}
+		return def
+	}
+	if _, ok := values['s']; ok {
+		return time.Unix(int64(number('s', 0)), 0).In(p.loc)
+	}
+	found := false
+	for _, verb := range []byte("YmdHMSjbVG") {
+		if _, ok := values[verb]; ok {
+			found = true
+		}
+	}
+	if !found {
+		return time.Time{}
+	}
+	year := number('Y', number('G', 0))
+	month := time.Month(number('m', 1))
+	if b, ok := values['b']; ok {
+		month = time.Month(strings.Index("JanFebMarAprMayJunJulAugSepOctNovDec", b)/3 + 1)
+	}
+	t := time.Date(year, month, number('d', 1), number('H', 0), number('M', 0), number('S', 0), 0, p.loc)
+	if _, ok := values['j']; ok {
+		t = t.AddDate(0, 0, number('j', 1)-t.YearDay())
+	}
+	if _, ok := values['V']; ok && !hasAny(values, "mdbj") {
+		// the Monday of the ISO week: January 4th is always in week 1
+		jan4 := time.Date(number('G', year), 1, 4, t.Hour(), t.Minute(), t.Second(), 0, p.loc)
+		monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+		t = monday.AddDate(0, 0, (number('V', 1)-1)*7)
+	}
+	return t
+}
+
+func hasAny(values map[byte]string, verbs string) bool {
+	for i := 0; i < len(verbs); i++ {
+		if _, ok := values[verbs[i]]; ok {
+			return true
+		}
+	}
+	return false
+}
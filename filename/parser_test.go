@@ -0,0 +1,114 @@
+package filename
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func parserFor(t *testing.T, config state.Config) *Parser {
+	p, err := newParser(&state.State{FullName: "/logs/app.log", Config: config}, time.UTC)
+	assert.NoError(t, err)
+	return p
+}
+
+func TestParserInvertsTheGenerator(t *testing.T) {
+	now := time.Date(2018, 12, 9, 15, 21, 32, 0, time.UTC)
+	for _, suffix := range []string{"%c", "%4c", "%Y%m%d%H%M%S-%c", "%s-%c", "%h-%p-%Y%m%d%H%M%S-%c"} {
+		s := &state.State{FullName: "/logs/app.log", Counter: 12, RotatedAt: now, Config: state.Config{Suffix: suffix, UTC: true}}
+		name := newTestGenerator().FileName(s)
+		m, ok := parserFor(t, s.Config).Parse(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, 12, m.Counter, name)
+		if !m.Time.IsZero() {
+			assert.Equal(t, now, m.Time, name)
+		}
+	}
+}
+
+func TestParserExtractsCounterAndTime(t *testing.T) {
+	p := parserFor(t, state.Config{Suffix: "%Y%m%d%H%M%S-%c", UTC: true})
+	m, ok := p.Parse("/logs/app.20181209152132-7-2.log.gz")
+	assert.True(t, ok)
+	assert.Equal(t, &Match{
+		Time:        time.Date(2018, 12, 9, 15, 21, 32, 0, time.UTC),
+		Counter:     7,
+		Index:       2,
+		Compression: "gzip",
+	}, m)
+}
+
+func TestParserExtractsTheHostname(t *testing.T) {
+	m, ok := parserFor(t, state.Config{Suffix: "%h-%c"}).Parse("/logs/app.web1-3.log")
+	assert.True(t, ok)
+	assert.Equal(t, "web1", m.Host)
+	assert.Equal(t, 3, m.Counter)
+}
+
+func TestParserRebuildsTimeFromCoarseTokens(t *testing.T) {
+	for _, c := range []struct {
+		suffix   string
+		name     string
+		expected string
+	}{
+		{"%G-W%V", "/logs/app.2018-W50.log", "2018-12-10"},
+		{"%Y%j", "/logs/app.2018343.log", "2018-12-09"},
+		{"%d%b%Y", "/logs/app.09Dec2018.log", "2018-12-09"},
+		{"%s", "/logs/app.1544313600.log", "2018-12-09"},
+	} {
+		m, ok := parserFor(t, state.Config{Suffix: c.suffix, UTC: true}).Parse(c.name)
+		assert.True(t, ok, c.name)
+		assert.Equal(t, c.expected, m.Time.Format("2006-01-02"), c.name)
+	}
+}
+
+func TestParserWithoutTimeTokens(t *testing.T) {
+	m, ok := parserFor(t, state.Config{Suffix: "%c"}).Parse("/logs/app.3.log")
+	assert.True(t, ok)
+	assert.True(t, m.Time.IsZero())
+	assert.Equal(t, 3, m.Counter)
+	m, ok = parserFor(t, state.Config{Suffix: "old"}).Parse("/logs/app.old.log")
+	assert.True(t, ok)
+	assert.Equal(t, -1, m.Counter)
+}
+
+func TestParserRejectsOtherFiles(t *testing.T) {
+	p := parserFor(t, state.Config{Suffix: "%c"})
+	for _, name := range []string{"/logs/app.log", "/logs/app..log", "/logs/app.-1.log", "/logs/app.x.log", "/logs/app.1.log.merge", "/logs/other.1.log", "/other/app.1.log", "/logs/app.1.log.xz"} {
+		_, ok := p.Parse(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestParserWithArchiveDir(t *testing.T) {
+	p := parserFor(t, state.Config{Suffix: "%d", ArchiveDir: "/archive/%Y/%m/", UTC: true})
+	assert.Equal(t, "/archive", p.Root())
+	assert.Equal(t, 2, p.Depth())
+	m, ok := p.Parse("/archive/2018/12/app.09.log.zst")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2018, 12, 9, 0, 0, 0, 0, time.UTC), m.Time)
+	assert.Equal(t, "zstd", m.Compression)
+	p = parserFor(t, state.Config{Suffix: "%c", ArchiveDir: "old"})
+	assert.Equal(t, "/logs/old", p.Root())
+	assert.Equal(t, 0, p.Depth())
+	_, ok = p.Parse("/logs/old/app.1.log")
+	assert.True(t, ok)
+}
+
+func TestParserWithShiftedArchives(t *testing.T) {
+	p := parserFor(t, state.Config{Naming: state.NamingShift})
+	assert.True(t, p.Shifted())
+	m, ok := p.Parse("/logs/app.log.12.bz2")
+	assert.True(t, ok)
+	assert.Equal(t, 12, m.Index)
+	assert.Equal(t, "bzip2", m.Compression)
+	_, ok = p.Parse("/logs/app.log")
+	assert.False(t, ok)
+}
+
+func TestParserRejectsInvalidSuffixes(t *testing.T) {
+	_, err := newParser(&state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%q"}}, time.UTC)
+	assert.Error(t, err)
+}
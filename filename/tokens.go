@@ -0,0 +1,124 @@
+package filename
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/state"
+)
+
+// renderContext holds the values available to tokens; time is already in the
+// configured location.
+type renderContext struct {
+	state    *state.State
+	time     time.Time
+	hostname string
+	pid      int
+}
+
+type patternTranslator func(c *renderContext, width int) string
+
+// tokenSpec describes a suffix token: how it's rendered, the longest time span
+// in which it keeps the same value (zero if it changes at every rotation,
+// negative if it never changes) and whether it accepts a width.
+type tokenSpec struct {
+	translate  patternTranslator
+	resolution time.Duration
+	padded     bool
+}
+
+const (
+	day   = time.Hour * 24
+	week  = day * 7
+	month = day * 31
+	year  = day * 366
+)
+
+func twoDigits(v int) string {
+	return fmt.Sprintf("%02d", v)
+}
+
+var tokenTable = map[byte]tokenSpec{
+	'c': {func(c *renderContext, width int) string { return fmt.Sprintf("%0*d", width, c.state.Counter) }, 0, true},
+	'Y': {func(c *renderContext, width int) string { return strconv.Itoa(c.time.Year()) }, year, false},
+	'm': {func(c *renderContext, width int) string { return twoDigits(int(c.time.Month())) }, month, false},
+	'd': {func(c *renderContext, width int) string { return twoDigits(c.time.Day()) }, day, false},
+	'H': {func(c *renderContext, width int) string { return twoDigits(c.time.Hour()) }, time.Hour, false},
+	'M': {func(c *renderContext, width int) string { return twoDigits(c.time.Minute()) }, time.Minute, false},
+	'S': {func(c *renderContext, width int) string { return twoDigits(c.time.Second()) }, time.Second, false},
+	's': {func(c *renderContext, width int) string { return strconv.FormatInt(c.time.Unix(), 10) }, time.Second, false},
+	'j': {func(c *renderContext, width int) string { return fmt.Sprintf("%03d", c.time.YearDay()) }, day, false},
+	'b': {func(c *renderContext, width int) string { return c.time.Month().String()[:3] }, month, false},
+	'V': {func(c *renderContext, width int) string { _, w := c.time.ISOWeek(); return twoDigits(w) }, week, false},
+	'G': {func(c *renderContext, width int) string { y, _ := c.time.ISOWeek(); return strconv.Itoa(y) }, year, false},
+	'h': {func(c *renderContext, width int) string { return c.hostname }, -1, false},
+	'p': {func(c *renderContext, width int) string { return strconv.Itoa(c.pid) }, -1, false},
+}
+
+type token struct {
+	literal string
+	verb    byte
+	width   int
+}
+
+func (t token) isLiteral() bool {
+	return t.verb == 0
+}
+
+// tokenize splits a suffix in literals and tokens; %% is a literal percent
+// sign and %Nc is a counter padded to N digits. Tokens that cannot be parsed
+// are kept as literals and the first problem is returned as an error, so
+// that the result is usable even when the suffix is invalid.
+func tokenize(suffix string) ([]token, error) {
+	tokens := make([]token, 0)
+	literal := make([]byte, 0)
+	var firstErr error
+	fail := func(err error, text string) {
+		if firstErr == nil {
+			firstErr = err
+		}
+		literal = append(literal, text...)
+	}
+	for i := 0; i < len(suffix); i++ {
+		if suffix[i] != '%' {
+			literal = append(literal, suffix[i])
+			continue
+		}
+		start := i
+		i++
+		for i < len(suffix) && suffix[i] >= '0' && suffix[i] <= '9' {
+			i++
+		}
+		if i == len(suffix) {
+			fail(fmt.Errorf("Suffix %s ends with an incomplete token", suffix), suffix[start:])
+			break
+		}
+		width := 0
+		if i > start+1 {
+			width, _ = strconv.Atoi(suffix[start+1 : i])
+		}
+		if suffix[i] == '%' && width == 0 {
+			literal = append(literal, '%')
+			continue
+		}
+		spec, ok := tokenTable[suffix[i]]
+		if !ok {
+			fail(fmt.Errorf("Unknown token %s in suffix %s", suffix[start:i+1], suffix), suffix[start:i+1])
+			continue
+		}
+		if width > 0 && !spec.padded {
+			fail(fmt.Errorf("Token %%%c doesn't support a width in suffix %s", suffix[i], suffix), suffix[start:i+1])
+			continue
+		}
+		if len(literal) > 0 {
+			tokens = append(tokens, token{literal: string(literal)})
+			literal = make([]byte, 0)
+		}
+		tokens = append(tokens, token{verb: suffix[i], width: width})
+	}
+	if len(literal) > 0 {
+		tokens = append(tokens, token{literal: string(literal)})
+	}
+	return tokens, firstErr
+}
@@ -0,0 +1,21 @@
+package filename
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := tokenize("a%%%cb%Y")
+	assert.NoError(t, err)
+	assert.Equal(t, []token{{literal: "a%"}, {verb: 'c'}, {literal: "b"}, {verb: 'Y'}}, tokens)
+}
+
+func TestTokenizeWidth(t *testing.T) {
+	tokens, err := tokenize("%04c-%Y")
+	assert.NoError(t, err)
+	assert.Equal(t, []token{{verb: 'c', width: 4}, {literal: "-"}, {verb: 'Y'}}, tokens)
+	_, err = tokenize("%2Y")
+	assert.EqualError(t, err, "Token %Y doesn't support a width in suffix %2Y")
+}
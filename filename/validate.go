@@ -0,0 +1,51 @@
+package filename
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// Validate checks that a suffix contains only known tokens. It returns warnings
+// if the suffix can produce the same name for two rotations, i.e. it doesn't
+// contain %c and its finest time token is coarser than the interval.
+func Validate(suffix string, interval time.Duration) ([]string, error) {
+	tokens, err := tokenize(suffix)
+	if err != nil {
+		return nil, err
+	}
+	resolution := time.Duration(-1)
+	for _, t := range tokens {
+		if t.isLiteral() {
+			continue
+		}
+		r := tokenTable[t.verb].resolution
+		if r == 0 {
+			return []string{}, nil
+		}
+		if r < 0 {
+			continue
+		}
+		if resolution < 0 || r < resolution {
+			resolution = r
+		}
+	}
+	if resolution < 0 {
+		return []string{fmt.Sprintf("Suffix %s contains neither time tokens nor %%c: every rotation produces the same name", suffix)}, nil
+	}
+	if resolution > interval {
+		return []string{fmt.Sprintf("Suffix %s changes every %s, less often than the interval %s: rotated files may collide (consider adding %%c)", suffix, resolution, interval)}, nil
+	}
+	return []string{}, nil
+}
+
+// ValidateDir checks that an archive directory template contains only known
+// tokens.
+func ValidateDir(dir string) error {
+	_, err := tokenize(dir)
+	if err != nil {
+		return utils.Wrapf(err, "Invalid archive directory %s", dir)
+	}
+	return nil
+}
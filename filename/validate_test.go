@@ -0,0 +1,62 @@
+package filename
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAcceptsValidSuffixes(t *testing.T) {
+	for suffix, interval := range map[string]time.Duration{
+		"%c":           time.Minute,
+		"%Y%m%d":       time.Hour * 24,
+		"%Y%m%d%H":     time.Hour,
+		"%Y%m%d-%c":    time.Hour,
+		"%%%Y%m%d%H%M": time.Minute * 5,
+		"%Y%m":         time.Hour * 24 * 31,
+	} {
+		warnings, err := Validate(suffix, interval)
+		assert.NoError(t, err, suffix)
+		assert.Empty(t, warnings, suffix)
+	}
+}
+
+func TestValidateRejectsUnknownTokens(t *testing.T) {
+	_, err := Validate("%Y%q", time.Hour)
+	assert.EqualError(t, err, "Unknown token %q in suffix %Y%q")
+	_, err = Validate("%Y%", time.Hour)
+	assert.EqualError(t, err, "Suffix %Y% ends with an incomplete token")
+}
+
+func TestValidateWarnsAboutCollisions(t *testing.T) {
+	warnings, err := Validate("%Y%m%d", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Suffix %Y%m%d changes every 24h0m0s, less often than the interval 1h0m0s: rotated files may collide (consider adding %c)"}, warnings)
+	warnings, err = Validate("%Y%m", time.Hour*24*30)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(warnings))
+	warnings, err = Validate("foo%%c", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Suffix foo%%c contains neither time tokens nor %c: every rotation produces the same name"}, warnings)
+}
+
+func TestValidateRicherTokens(t *testing.T) {
+	warnings, err := Validate("%h-%s", time.Second)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	warnings, err = Validate("%G%V", time.Hour*24)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(warnings))
+	warnings, err = Validate("%h-%p", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(warnings))
+	warnings, err = Validate("%4c", time.Hour)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func TestValidateDir(t *testing.T) {
+	assert.NoError(t, ValidateDir("/var/log/archive/%Y/%m"))
+	assert.EqualError(t, ValidateDir("/var/log/%q"), "Invalid archive directory /var/log/%q: Unknown token %q in suffix /var/log/%q")
+}
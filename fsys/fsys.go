@@ -0,0 +1,42 @@
+package fsys
+
+import (
+	"io"
+	"os"
+)
+
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Name() string
+	Sync() error
+	Stat() (os.FileInfo, error)
+}
+
+// FileSystem abstracts the file operations used by loco, so that they can be
+// performed on disk or in memory.
+type FileSystem interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Rename(oldpath string, newpath string) error
+	Remove(name string) error
+	Stat(name string) (os.FileInfo, error)
+	MkdirAll(path string, perm os.FileMode) error
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	ReadDir(dirname string) ([]os.FileInfo, error)
+	SyncDir(dirname string) error
+	Chmod(name string, mode os.FileMode) error
+	Chown(name string, uid int, gid int) error
+}
+
+// SameFile tells whether two FileInfo describe the same file, as os.SameFile
+// does, also for files in memory.
+func SameFile(fi1 os.FileInfo, fi2 os.FileInfo) bool {
+	m1, ok1 := fi1.(*memFileInfo)
+	m2, ok2 := fi2.(*memFileInfo)
+	if ok1 || ok2 {
+		return ok1 && ok2 && m1.node == m2.node
+	}
+	return os.SameFile(fi1, fi2)
+}
@@ -0,0 +1,516 @@
+package fsys
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Op string
+
+const (
+	OpOpen    Op = "open"
+	OpRead    Op = "read"
+	OpWrite   Op = "write"
+	OpSync    Op = "sync"
+	OpRename  Op = "rename"
+	OpRemove  Op = "remove"
+	OpStat    Op = "stat"
+	OpMkdir   Op = "mkdir"
+	OpReadDir Op = "readdir"
+	OpChmod   Op = "chmod"
+	OpChown   Op = "chown"
+)
+
+type memNode struct {
+	dir     bool
+	data    []byte
+	synced  []byte
+	entries map[string]*memNode
+	mode    os.FileMode
+	modTime time.Time
+	uid     int
+	gid     int
+}
+
+type memFileInfo struct {
+	node    *memNode
+	name    string
+	dir     bool
+	mode    os.FileMode
+	modTime time.Time
+	size    int64
+}
+
+func newMemFileInfo(name string, node *memNode) *memFileInfo {
+	return &memFileInfo{
+		node:    node,
+		name:    filepath.Base(key(name)),
+		dir:     node.dir,
+		mode:    node.mode,
+		modTime: node.modTime,
+		size:    int64(len(node.data)),
+	}
+}
+
+func (fi *memFileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *memFileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *memFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return fi.mode | os.ModeDir
+	}
+	return fi.mode
+}
+
+func (fi *memFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *memFileInfo) IsDir() bool {
+	return fi.dir
+}
+
+func (fi *memFileInfo) Sys() interface{} {
+	return nil
+}
+
+type fault struct {
+	op   Op
+	name string
+}
+
+// MemFileSystem is an in-memory FileSystem. Faults can be injected for any
+// operation and Crash simulates a power loss, discarding unsynced data and
+// the directory entries created, renamed or removed since the directory was
+// last synced.
+type MemFileSystem struct {
+	mu     sync.Mutex
+	nodes  map[string]*memNode
+	faults map[fault]error
+	now    func() time.Time
+}
+
+func key(name string) string {
+	return filepath.Join("/", name)
+}
+
+func (fs *MemFileSystem) fault(op Op, name string) error {
+	err, ok := fs.faults[fault{op, key(name)}]
+	if !ok {
+		err, ok = fs.faults[fault{op, ""}]
+	}
+	if !ok {
+		return nil
+	}
+	return &os.PathError{Op: string(op), Path: name, Err: err}
+}
+
+// InjectFault makes every subsequent op on name fail with err; an empty name
+// matches any file.
+func (fs *MemFileSystem) InjectFault(op Op, name string, err error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if name != "" {
+		name = key(name)
+	}
+	fs.faults[fault{op, name}] = err
+}
+
+func (fs *MemFileSystem) ClearFaults() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.faults = make(map[fault]error)
+}
+
+// SetClock sets the function used to compute modification times.
+func (fs *MemFileSystem) SetClock(now func() time.Time) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.now = now
+}
+
+// Crash reverts every directory to the entries it had when it was last synced
+// and every file to the content it had when it was last synced.
+func (fs *MemFileSystem) Crash() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	nodes := make(map[string]*memNode)
+	var restore func(name string, node *memNode)
+	restore = func(name string, node *memNode) {
+		nodes[name] = node
+		if !node.dir {
+			node.data = append([]byte(nil), node.synced...)
+			return
+		}
+		for child, n := range node.entries {
+			restore(filepath.Join(name, child), n)
+		}
+	}
+	restore("/", fs.nodes["/"])
+	fs.nodes = nodes
+}
+
+func (fs *MemFileSystem) parentDir(op Op, name string) error {
+	parent, ok := fs.nodes[filepath.Dir(key(name))]
+	if !ok {
+		return &os.PathError{Op: string(op), Path: name, Err: syscall.ENOENT}
+	}
+	if !parent.dir {
+		return &os.PathError{Op: string(op), Path: name, Err: syscall.ENOTDIR}
+	}
+	return nil
+}
+
+func (fs *MemFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.fault(OpOpen, name); err != nil {
+		return nil, err
+	}
+	node, ok := fs.nodes[key(name)]
+	if ok && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		return nil, &os.PathError{Op: string(OpOpen), Path: name, Err: syscall.EEXIST}
+	}
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, &os.PathError{Op: string(OpOpen), Path: name, Err: syscall.ENOENT}
+		}
+		if err := fs.parentDir(OpOpen, name); err != nil {
+			return nil, err
+		}
+		node = &memNode{mode: perm.Perm(), modTime: fs.now()}
+		fs.nodes[key(name)] = node
+	}
+	if node.dir && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return nil, &os.PathError{Op: string(OpOpen), Path: name, Err: syscall.EISDIR}
+	}
+	if flag&os.O_TRUNC != 0 {
+		node.data = nil
+		node.modTime = fs.now()
+	}
+	return &memFile{fs: fs, name: name, node: node, flag: flag}, nil
+}
+
+func (fs *MemFileSystem) Rename(oldpath string, newpath string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	linkError := func(err error) error {
+		return &os.LinkError{Op: string(OpRename), Old: oldpath, New: newpath, Err: err}
+	}
+	for _, name := range []string{oldpath, newpath} {
+		if err := fs.fault(OpRename, name); err != nil {
+			return linkError(err.(*os.PathError).Err)
+		}
+	}
+	oldKey, newKey := key(oldpath), key(newpath)
+	node, ok := fs.nodes[oldKey]
+	if !ok {
+		return linkError(syscall.ENOENT)
+	}
+	if err := fs.parentDir(OpRename, newpath); err != nil {
+		return linkError(err.(*os.PathError).Err)
+	}
+	if target, ok := fs.nodes[newKey]; ok && target.dir {
+		return linkError(syscall.EEXIST)
+	}
+	if oldKey == newKey {
+		return nil
+	}
+	delete(fs.nodes, oldKey)
+	fs.nodes[newKey] = node
+	if node.dir {
+		prefix := oldKey + "/"
+		for k, n := range fs.nodes {
+			if strings.HasPrefix(k, prefix) {
+				delete(fs.nodes, k)
+				fs.nodes[newKey+"/"+k[len(prefix):]] = n
+			}
+		}
+	}
+	return nil
+}
+
+func (fs *MemFileSystem) hasChildren(dir string) bool {
+	for k := range fs.nodes {
+		if k != dir && filepath.Dir(k) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+func (fs *MemFileSystem) Remove(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.fault(OpRemove, name); err != nil {
+		return err
+	}
+	node, ok := fs.nodes[key(name)]
+	if !ok {
+		return &os.PathError{Op: string(OpRemove), Path: name, Err: syscall.ENOENT}
+	}
+	if node.dir && fs.hasChildren(key(name)) {
+		return &os.PathError{Op: string(OpRemove), Path: name, Err: syscall.ENOTEMPTY}
+	}
+	delete(fs.nodes, key(name))
+	return nil
+}
+
+func (fs *MemFileSystem) Stat(name string) (os.FileInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.fault(OpStat, name); err != nil {
+		return nil, err
+	}
+	node, ok := fs.nodes[key(name)]
+	if !ok {
+		return nil, &os.PathError{Op: string(OpStat), Path: name, Err: syscall.ENOENT}
+	}
+	return newMemFileInfo(name, node), nil
+}
+
+func (fs *MemFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.fault(OpMkdir, path); err != nil {
+		return err
+	}
+	dir := key(path)
+	missing := make([]string, 0)
+	for {
+		node, ok := fs.nodes[dir]
+		if ok {
+			if !node.dir {
+				return &os.PathError{Op: string(OpMkdir), Path: path, Err: syscall.ENOTDIR}
+			}
+			break
+		}
+		missing = append(missing, dir)
+		dir = filepath.Dir(dir)
+	}
+	for _, d := range missing {
+		fs.nodes[d] = &memNode{dir: true, mode: perm.Perm(), modTime: fs.now()}
+	}
+	return nil
+}
+
+func (fs *MemFileSystem) ReadFile(name string) ([]byte, error) {
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func (fs *MemFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	f, err := fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (fs *MemFileSystem) ReadDir(dirname string) ([]os.FileInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.fault(OpReadDir, dirname); err != nil {
+		return nil, err
+	}
+	dir := key(dirname)
+	node, ok := fs.nodes[dir]
+	if !ok {
+		return nil, &os.PathError{Op: string(OpReadDir), Path: dirname, Err: syscall.ENOENT}
+	}
+	if !node.dir {
+		return nil, &os.PathError{Op: string(OpReadDir), Path: dirname, Err: syscall.ENOTDIR}
+	}
+	infos := make([]os.FileInfo, 0)
+	for k, n := range fs.nodes {
+		if k != dir && filepath.Dir(k) == dir {
+			infos = append(infos, newMemFileInfo(k, n))
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+func (fs *MemFileSystem) SyncDir(dirname string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.fault(OpSync, dirname); err != nil {
+		return err
+	}
+	node, ok := fs.nodes[key(dirname)]
+	if !ok {
+		return &os.PathError{Op: string(OpSync), Path: dirname, Err: syscall.ENOENT}
+	}
+	if !node.dir {
+		return &os.PathError{Op: string(OpSync), Path: dirname, Err: syscall.ENOTDIR}
+	}
+	dir := key(dirname)
+	node.entries = make(map[string]*memNode)
+	for k, n := range fs.nodes {
+		if k != dir && filepath.Dir(k) == dir {
+			node.entries[filepath.Base(k)] = n
+		}
+	}
+	return nil
+}
+
+func (fs *MemFileSystem) Chmod(name string, mode os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.fault(OpChmod, name); err != nil {
+		return err
+	}
+	node, ok := fs.nodes[key(name)]
+	if !ok {
+		return &os.PathError{Op: string(OpChmod), Path: name, Err: syscall.ENOENT}
+	}
+	node.mode = mode.Perm()
+	return nil
+}
+
+// Chown changes the owner of a file; as in os.Chown, a negative id leaves
+// the owner or the group unchanged.
+func (fs *MemFileSystem) Chown(name string, uid int, gid int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.fault(OpChown, name); err != nil {
+		return err
+	}
+	node, ok := fs.nodes[key(name)]
+	if !ok {
+		return &os.PathError{Op: string(OpChown), Path: name, Err: syscall.ENOENT}
+	}
+	if uid >= 0 {
+		node.uid = uid
+	}
+	if gid >= 0 {
+		node.gid = gid
+	}
+	return nil
+}
+
+// Owner returns the owner and the group of a file.
+func (fs *MemFileSystem) Owner(name string) (int, int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	node, ok := fs.nodes[key(name)]
+	if !ok {
+		return 0, 0, &os.PathError{Op: string(OpStat), Path: name, Err: syscall.ENOENT}
+	}
+	return node.uid, node.gid, nil
+}
+
+func NewMemFileSystem() *MemFileSystem {
+	return &MemFileSystem{
+		nodes:  map[string]*memNode{"/": {dir: true, mode: 0755}},
+		faults: make(map[fault]error),
+		now:    time.Now,
+	}
+}
+
+type memFile struct {
+	fs     *MemFileSystem
+	name   string
+	node   *memNode
+	flag   int
+	offset int
+	closed bool
+}
+
+func (f *memFile) check(op Op) error {
+	if f.closed {
+		return &os.PathError{Op: string(op), Path: f.name, Err: os.ErrClosed}
+	}
+	return f.fs.fault(op, f.name)
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(OpRead); err != nil {
+		return 0, err
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, &os.PathError{Op: string(OpRead), Path: f.name, Err: syscall.EBADF}
+	}
+	if f.offset >= len(f.node.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += n
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(OpWrite); err != nil {
+		return 0, err
+	}
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &os.PathError{Op: string(OpWrite), Path: f.name, Err: syscall.EBADF}
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = len(f.node.data)
+	}
+	end := f.offset + len(p)
+	if end > len(f.node.data) {
+		f.node.data = append(f.node.data, make([]byte, end-len(f.node.data))...)
+	}
+	copy(f.node.data[f.offset:], p)
+	f.offset = end
+	f.node.modTime = f.fs.now()
+	return len(p), nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return &os.PathError{Op: "close", Path: f.name, Err: os.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
+
+func (f *memFile) Name() string {
+	return f.name
+}
+
+func (f *memFile) Sync() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(OpSync); err != nil {
+		return err
+	}
+	f.node.synced = append([]byte(nil), f.node.data...)
+	return nil
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(OpStat); err != nil {
+		return nil, err
+	}
+	return newMemFileInfo(f.name, f.node), nil
+}
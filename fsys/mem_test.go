@@ -0,0 +1,179 @@
+package fsys
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemWriteAndReadFile(t *testing.T) {
+	fs := NewMemFileSystem()
+	assert.NoError(t, fs.MkdirAll("/path/to", 0755))
+	assert.NoError(t, fs.WriteFile("/path/to/file", []byte("foo"), 0644))
+	b, err := fs.ReadFile("/path/to/file")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), b)
+	fi, err := fs.Stat("/path/to/file")
+	assert.NoError(t, err)
+	assert.Equal(t, "file", fi.Name())
+	assert.Equal(t, int64(3), fi.Size())
+	assert.Equal(t, os.FileMode(0644), fi.Mode())
+	assert.False(t, fi.IsDir())
+}
+
+func TestMemOpenFileRequiresParentDirectory(t *testing.T) {
+	fs := NewMemFileSystem()
+	_, err := fs.OpenFile("/path/to/file", os.O_CREATE|os.O_WRONLY, 0644)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMemAppend(t *testing.T) {
+	fs := NewMemFileSystem()
+	assert.NoError(t, fs.WriteFile("/file", []byte("foo"), 0644))
+	f, err := fs.OpenFile("/file", os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte("bar"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	_, err = f.Write([]byte("baz"))
+	assert.Error(t, err)
+	b, err := fs.ReadFile("/file")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foobar"), b)
+}
+
+func TestMemRenameKeepsOpenFiles(t *testing.T) {
+	fs := NewMemFileSystem()
+	f, err := fs.OpenFile("/file", os.O_CREATE|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	assert.NoError(t, fs.Rename("/file", "/file.1"))
+	_, err = f.Write([]byte("foo"))
+	assert.NoError(t, err)
+	_, err = fs.Stat("/file")
+	assert.True(t, os.IsNotExist(err))
+	b, err := fs.ReadFile("/file.1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), b)
+}
+
+func TestMemRenameDirectory(t *testing.T) {
+	fs := NewMemFileSystem()
+	assert.NoError(t, fs.MkdirAll("/a/b", 0755))
+	assert.NoError(t, fs.WriteFile("/a/b/file", []byte("foo"), 0644))
+	assert.NoError(t, fs.Rename("/a", "/c"))
+	b, err := fs.ReadFile("/c/b/file")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), b)
+}
+
+func TestMemRemove(t *testing.T) {
+	fs := NewMemFileSystem()
+	assert.NoError(t, fs.MkdirAll("/dir", 0755))
+	assert.NoError(t, fs.WriteFile("/dir/file", []byte("foo"), 0644))
+	err := fs.Remove("/dir")
+	assert.Equal(t, syscall.ENOTEMPTY, err.(*os.PathError).Err)
+	assert.NoError(t, fs.Remove("/dir/file"))
+	assert.NoError(t, fs.Remove("/dir"))
+	assert.True(t, os.IsNotExist(fs.Remove("/dir")))
+}
+
+func TestMemReadDir(t *testing.T) {
+	fs := NewMemFileSystem()
+	fs.SetClock(func() time.Time { return time.Unix(42, 0) })
+	assert.NoError(t, fs.MkdirAll("/dir/sub/subsub", 0755))
+	assert.NoError(t, fs.WriteFile("/dir/b", []byte("foo"), 0644))
+	assert.NoError(t, fs.WriteFile("/dir/a", []byte("bar"), 0644))
+	infos, err := fs.ReadDir("/dir")
+	assert.NoError(t, err)
+	names := make([]string, 0)
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+		assert.Equal(t, time.Unix(42, 0), fi.ModTime())
+	}
+	assert.Equal(t, []string{"a", "b", "sub"}, names)
+	assert.True(t, infos[2].IsDir())
+}
+
+func TestMemInjectFault(t *testing.T) {
+	fs := NewMemFileSystem()
+	fs.InjectFault(OpWrite, "/file", syscall.ENOSPC)
+	fs.InjectFault(OpRename, "", syscall.EACCES)
+	f, err := fs.OpenFile("/file", os.O_CREATE|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte("foo"))
+	assert.Equal(t, syscall.ENOSPC, err.(*os.PathError).Err)
+	err = fs.Rename("/file", "/file.1")
+	assert.True(t, os.IsPermission(err))
+	fs.ClearFaults()
+	_, err = f.Write([]byte("foo"))
+	assert.NoError(t, err)
+	assert.NoError(t, fs.Rename("/file", "/file.1"))
+}
+
+func TestMemCrashDiscardsUnsyncedData(t *testing.T) {
+	fs := NewMemFileSystem()
+	f, err := fs.OpenFile("/file", os.O_CREATE|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	f.Write([]byte("foo"))
+	assert.NoError(t, f.Sync())
+	assert.NoError(t, fs.SyncDir("/"))
+	f.Write([]byte("bar"))
+	fs.Crash()
+	b, err := fs.ReadFile("/file")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), b)
+}
+
+func TestMemCrashDiscardsUnsyncedDirectoryEntries(t *testing.T) {
+	fs := NewMemFileSystem()
+	assert.NoError(t, fs.MkdirAll("/dir", 0755))
+	assert.NoError(t, fs.WriteFile("/dir/old", []byte("foo"), 0644))
+	assert.NoError(t, fs.WriteFile("/dir/removed", []byte("bar"), 0644))
+	assert.NoError(t, fs.SyncDir("/"))
+	assert.NoError(t, fs.SyncDir("/dir"))
+	assert.NoError(t, fs.Rename("/dir/old", "/dir/new"))
+	assert.NoError(t, fs.Remove("/dir/removed"))
+	assert.NoError(t, fs.WriteFile("/dir/created", []byte("baz"), 0644))
+	assert.NoError(t, fs.MkdirAll("/other", 0755))
+	fs.Crash()
+	infos, err := fs.ReadDir("/dir")
+	assert.NoError(t, err)
+	names := make([]string, 0)
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	assert.Equal(t, []string{"old", "removed"}, names)
+	_, err = fs.Stat("/other")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMemChmodAndChown(t *testing.T) {
+	fs := NewMemFileSystem()
+	assert.NoError(t, fs.WriteFile("/file", []byte("foo"), 0644))
+	assert.NoError(t, fs.Chmod("/file", 0640))
+	assert.NoError(t, fs.Chown("/file", 1000, 100))
+	assert.NoError(t, fs.Chown("/file", -1, 200))
+	info, err := fs.Stat("/file")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode())
+	uid, gid, err := fs.Owner("/file")
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, uid)
+	assert.Equal(t, 200, gid)
+	assert.True(t, os.IsNotExist(fs.Chmod("/missing", 0640)))
+}
+
+func TestMemSameFile(t *testing.T) {
+	fs := NewMemFileSystem()
+	fs.WriteFile("/file", []byte("foo"), 0644)
+	before, _ := fs.Stat("/file")
+	fs.Rename("/file", "/file.1")
+	renamed, _ := fs.Stat("/file.1")
+	fs.WriteFile("/file", []byte("bar"), 0644)
+	after, _ := fs.Stat("/file")
+	assert.True(t, SameFile(before, renamed))
+	assert.False(t, SameFile(before, after))
+}
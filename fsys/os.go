@@ -0,0 +1,70 @@
+package fsys
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+type osFileSystem struct{}
+
+func (fs *osFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (fs *osFileSystem) Rename(oldpath string, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (fs *osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (fs *osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (fs *osFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (fs *osFileSystem) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+func (fs *osFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(name, data, perm)
+}
+
+func (fs *osFileSystem) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(dirname)
+}
+
+func (fs *osFileSystem) SyncDir(dirname string) error {
+	d, err := os.Open(dirname)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (fs *osFileSystem) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (fs *osFileSystem) Chown(name string, uid int, gid int) error {
+	return os.Chown(name, uid, gid)
+}
+
+var osFS = &osFileSystem{}
+
+func NewOSFileSystem() FileSystem {
+	return osFS
+}
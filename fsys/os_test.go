@@ -0,0 +1,28 @@
+package fsys
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSFileSystem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fs := NewOSFileSystem()
+	assert.NoError(t, fs.MkdirAll(dir+"/sub", 0755))
+	assert.NoError(t, fs.WriteFile(dir+"/sub/file", []byte("foo"), 0644))
+	assert.NoError(t, fs.Rename(dir+"/sub/file", dir+"/sub/file.1"))
+	b, err := fs.ReadFile(dir + "/sub/file.1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), b)
+	infos, err := fs.ReadDir(dir + "/sub")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(infos))
+	assert.NoError(t, fs.Remove(dir+"/sub/file.1"))
+	_, err = fs.Stat(dir + "/sub/file.1")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -59,3 +59,25 @@ func Parse(interval string) (time.Duration, error) {
 	}
 	return time.Duration(value * nanoseconds(tokens[2])), nil
 }
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses an absolute time, in local time unless it has a zone, or
+// an interval before now (e.g. 2h).
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if d, err := Parse(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("Invalid time")
+}
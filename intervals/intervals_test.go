@@ -40,3 +40,18 @@ func TestParseOk(t *testing.T) {
 		assert.Equal(t, expected[i], interval)
 	}
 }
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2018, 12, 9, 15, 0, 0, 0, time.UTC)
+	v, err := ParseTime("2h", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-time.Hour*2), v)
+	v, err = ParseTime("2018-12-09T10:00:00Z", now)
+	assert.NoError(t, err)
+	assert.True(t, v.Equal(time.Date(2018, 12, 9, 10, 0, 0, 0, time.UTC)))
+	v, err = ParseTime("2018-12-08", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2018, 12, 8, 0, 0, 0, 0, time.Local), v)
+	_, err = ParseTime("yesterday", now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+package locotest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a clock that only moves when told to; it can be shared between
+// goroutines.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func (c *Clock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
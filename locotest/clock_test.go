@@ -0,0 +1,17 @@
+package locotest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	c := NewClock(time.Unix(0, 0))
+	assert.Equal(t, time.Unix(0, 0), c.Now())
+	assert.Equal(t, time.Unix(60, 0), c.Advance(time.Minute))
+	assert.Equal(t, time.Unix(60, 0), c.Now())
+	c.Set(time.Unix(42, 0))
+	assert.Equal(t, time.Unix(42, 0), c.Now())
+}
@@ -0,0 +1,56 @@
+package locotest
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+)
+
+// Files returns the sorted names of the regular files in dir.
+func Files(fs fsys.FileSystem, dir string) ([]string, error) {
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, info := range infos {
+		if !info.IsDir() {
+			names = append(names, info.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// AssertFiles checks that dir contains exactly the expected files.
+func AssertFiles(t testing.TB, fs fsys.FileSystem, dir string, expected ...string) bool {
+	t.Helper()
+	actual, err := Files(fs, dir)
+	if err != nil {
+		t.Errorf("Cannot list files in %s: %s", dir, err)
+		return false
+	}
+	expected = append([]string{}, expected...)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Unexpected files in %s: expected %v, actual %v", dir, expected, actual)
+		return false
+	}
+	return true
+}
+
+func AssertFileContent(t testing.TB, fs fsys.FileSystem, name string, expected string) bool {
+	t.Helper()
+	b, err := fs.ReadFile(name)
+	if err != nil {
+		t.Errorf("Cannot read %s: %s", name, err)
+		return false
+	}
+	if string(b) != expected {
+		t.Errorf("Unexpected content of %s: expected %q, actual %q", name, expected, string(b))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package locotest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertFiles(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs/sub", 0755)
+	fs.WriteFile("/logs/b.log", []byte("b"), 0644)
+	fs.WriteFile("/logs/a.log", []byte("a"), 0644)
+	rt := &recordingT{}
+	assert.True(t, AssertFiles(rt, fs, "/logs", "b.log", "a.log"))
+	assert.False(t, AssertFiles(rt, fs, "/logs", "a.log"))
+	assert.False(t, AssertFiles(rt, fs, "/missing"))
+	assert.Equal(t, 2, len(rt.errors))
+}
+
+func TestAssertFileContent(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.WriteFile("/file.log", []byte("foo"), 0644)
+	rt := &recordingT{}
+	assert.True(t, AssertFileContent(rt, fs, "/file.log", "foo"))
+	assert.False(t, AssertFileContent(rt, fs, "/file.log", "bar"))
+	assert.False(t, AssertFileContent(rt, fs, "/missing.log", ""))
+	assert.Equal(t, []string{
+		`Unexpected content of /file.log: expected "bar", actual "foo"`,
+		"Cannot read /missing.log: open /missing.log: no such file or directory",
+	}, rt.errors)
+}
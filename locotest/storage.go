@@ -0,0 +1,8 @@
+package locotest
+
+import "github.com/lorenzobenvenuti/loco/state"
+
+// NewStateStorage returns an in-memory StateStorage.
+func NewStateStorage() state.StateStorage {
+	return state.NewMapStorage()
+}
@@ -0,0 +1,24 @@
+package locotest
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+type Step struct {
+	After time.Duration
+	Write string
+}
+
+// Play runs a timeline: for each step the clock is advanced and the data is
+// written to w. Write errors fail the test.
+func Play(t testing.TB, clock *Clock, w io.Writer, steps ...Step) {
+	t.Helper()
+	for i, step := range steps {
+		clock.Advance(step.After)
+		if _, err := io.WriteString(w, step.Write); err != nil {
+			t.Fatalf("Step %d failed: %s", i, err)
+		}
+	}
+}
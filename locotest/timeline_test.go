@@ -0,0 +1,43 @@
+package locotest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/locotest"
+	"github.com/lorenzobenvenuti/loco/logwriter"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotationTimeline(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	clock := locotest.NewClock(time.Date(2018, 12, 9, 0, 0, 0, 0, time.UTC))
+	storage := locotest.NewStateStorage()
+	lw, err := logwriter.New(
+		"/logs/app.log",
+		logwriter.WithFileSystem(fs),
+		logwriter.WithStateStorage(storage),
+		logwriter.WithClock(clock),
+		logwriter.WithFileNameGenerator(filename.NewFileNameGenerator()),
+		logwriter.WithConfig(&state.Config{Interval: time.Hour * 24, Suffix: "%Y%m%d", UTC: true}),
+	)
+	assert.NoError(t, err)
+	locotest.Play(t, clock, lw,
+		locotest.Step{Write: "first\n"},
+		locotest.Step{After: time.Hour * 25, Write: "second\n"},
+		locotest.Step{After: time.Hour * 25, Write: "third\n"},
+	)
+	assert.NoError(t, lw.Close())
+	locotest.AssertFiles(t, fs, "/logs", "app.log", "app.20181210.log", "app.20181211.log")
+	locotest.AssertFileContent(t, fs, "/logs/app.20181210.log", "first\n")
+	locotest.AssertFileContent(t, fs, "/logs/app.20181211.log", "second\n")
+	locotest.AssertFileContent(t, fs, "/logs/app.log", "third\n")
+	states, err := storage.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(states))
+	assert.Equal(t, 2, states[0].Counter)
+}
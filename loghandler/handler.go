@@ -0,0 +1,111 @@
+package loghandler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/lorenzobenvenuti/loco/logwriter"
+)
+
+var ErrClosed = errors.New("Handler is closed")
+
+// sink is the log file shared by derived handlers: writes hold the read lock,
+// so that Close waits for the ones in progress and rejects the others.
+type sink struct {
+	mu     sync.RWMutex
+	writer *logwriter.LogWriter
+	closed bool
+}
+
+// Handler is a slog.Handler that writes records to a rotated log file.
+// Handlers derived with WithAttrs and WithGroup share the same file, so closing
+// any of them closes all.
+type Handler struct {
+	handler slog.Handler
+	sink    *sink
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	h.sink.mu.RLock()
+	defer h.sink.mu.RUnlock()
+	if h.sink.closed {
+		return ErrClosed
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{handler: h.handler.WithAttrs(attrs), sink: h.sink}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{handler: h.handler.WithGroup(name), sink: h.sink}
+}
+
+// Sync commits the records written so far to stable storage.
+func (h *Handler) Sync() error {
+	h.sink.mu.RLock()
+	defer h.sink.mu.RUnlock()
+	if h.sink.closed {
+		return ErrClosed
+	}
+	return h.sink.writer.Sync()
+}
+
+// Close syncs and closes the log file; records handled afterwards are rejected
+// with ErrClosed.
+func (h *Handler) Close() error {
+	h.sink.mu.Lock()
+	defer h.sink.mu.Unlock()
+	if h.sink.closed {
+		return nil
+	}
+	h.sink.closed = true
+	err := h.sink.writer.Sync()
+	if cerr := h.sink.writer.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func newHandler(lw *logwriter.LogWriter, f func(w io.Writer) slog.Handler) *Handler {
+	return &Handler{
+		handler: f(lw),
+		sink:    &sink{writer: lw},
+	}
+}
+
+func NewTextHandler(lw *logwriter.LogWriter, opts *slog.HandlerOptions) *Handler {
+	return newHandler(lw, func(w io.Writer) slog.Handler { return slog.NewTextHandler(w, opts) })
+}
+
+func NewJSONHandler(lw *logwriter.LogWriter, opts *slog.HandlerOptions) *Handler {
+	return newHandler(lw, func(w io.Writer) slog.Handler { return slog.NewJSONHandler(w, opts) })
+}
+
+// OpenTextHandler creates a LogWriter for the given file and returns a text
+// handler writing to it.
+func OpenTextHandler(fullName string, opts *slog.HandlerOptions, writerOpts ...logwriter.Option) (*Handler, error) {
+	lw, err := logwriter.New(fullName, writerOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewTextHandler(lw, opts), nil
+}
+
+// OpenJSONHandler creates a LogWriter for the given file and returns a JSON
+// handler writing to it.
+func OpenJSONHandler(fullName string, opts *slog.HandlerOptions, writerOpts ...logwriter.Option) (*Handler, error) {
+	lw, err := logwriter.New(fullName, writerOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONHandler(lw, opts), nil
+}
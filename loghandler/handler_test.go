@@ -0,0 +1,124 @@
+package loghandler
+
+import (
+	"context"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/logwriter"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func removeTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return a
+}
+
+func writerOptions() []logwriter.Option {
+	return []logwriter.Option{
+		logwriter.WithStateStorage(state.NewMapStorage()),
+		logwriter.WithConfig(state.NewConfig(time.Hour*24, "%c")),
+	}
+}
+
+func mustReadFile(t *testing.T, file string) string {
+	b, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestTextHandler(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	fullpath := path.Join(dir, "file.log")
+	h, err := OpenTextHandler(fullpath, &slog.HandlerOptions{ReplaceAttr: removeTime}, writerOptions()...)
+	assert.NoError(t, err)
+	logger := slog.New(h)
+	logger.Info("hello", "foo", "bar")
+	logger.Debug("not logged")
+	logger.With("a", 1).WithGroup("g").Warn("grouped", "b", 2)
+	assert.NoError(t, h.Close())
+	assert.Equal(
+		t,
+		"level=INFO msg=hello foo=bar\nlevel=WARN msg=grouped a=1 g.b=2\n",
+		mustReadFile(t, fullpath),
+	)
+}
+
+func TestJSONHandler(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	fullpath := path.Join(dir, "file.log")
+	h, err := OpenJSONHandler(
+		fullpath,
+		&slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: removeTime},
+		writerOptions()...,
+	)
+	assert.NoError(t, err)
+	logger := slog.New(h)
+	logger.Debug("debug")
+	logger.WithGroup("g").With("a", 1).Error("failed", "b", "c")
+	assert.NoError(t, h.Sync())
+	assert.NoError(t, h.Close())
+	lines := strings.Split(strings.TrimSpace(mustReadFile(t, fullpath)), "\n")
+	assert.Equal(t, []string{
+		`{"level":"DEBUG","msg":"debug"}`,
+		`{"level":"ERROR","msg":"failed","g":{"a":1,"b":"c"}}`,
+	}, lines)
+}
+
+func TestClosedHandlerRejectsRecords(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	fullpath := path.Join(dir, "file.log")
+	h, err := OpenTextHandler(fullpath, nil, writerOptions()...)
+	assert.NoError(t, err)
+	derived := h.WithAttrs([]slog.Attr{slog.String("a", "b")})
+	assert.NoError(t, h.Close())
+	assert.NoError(t, h.Close())
+	err = derived.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "message", 0))
+	assert.Equal(t, ErrClosed, err)
+	assert.Equal(t, ErrClosed, h.Sync())
+	assert.False(t, utils.Exists(fullpath))
+}
+
+func TestCloseWaitsForRecordsInProgress(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	fullpath := path.Join(dir, "file.log")
+	h, err := OpenTextHandler(fullpath, &slog.HandlerOptions{ReplaceAttr: removeTime}, writerOptions()...)
+	assert.NoError(t, err)
+	var handled atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "message", 0)) == nil {
+					handled.Add(1)
+				}
+			}
+		}()
+	}
+	for handled.Load() == 0 {
+		runtime.Gosched()
+	}
+	assert.NoError(t, h.Close())
+	closed := mustReadFile(t, fullpath)
+	wg.Wait()
+	assert.Equal(t, closed, mustReadFile(t, fullpath), "records written after Close")
+	assert.Equal(t, handled.Load(), int64(strings.Count(closed, "\n")))
+}
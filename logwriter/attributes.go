@@ -0,0 +1,51 @@
+package logwriter
+
+import (
+	"os"
+
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+const defaultFileMode = 0644
+
+func (lw *LogWriter) fileMode() os.FileMode {
+	if lw.state.Config.FileMode != 0 {
+		return lw.state.Config.FileMode
+	}
+	return defaultFileMode
+}
+
+// setAttributes applies the configured mode, owner and group to a file created
+// or archived by the writer. The mode is set explicitly since the one passed
+// when creating a file is subject to the umask.
+func (lw *LogWriter) setAttributes(name string) error {
+	c := lw.state.Config
+	if c.FileMode != 0 {
+		err := lw.fs.Chmod(name, c.FileMode)
+		if err != nil {
+			return utils.Wrapf(err, "Cannot change mode of %s", name)
+		}
+	}
+	if c.Owner == "" && c.Group == "" {
+		return nil
+	}
+	uid, gid := -1, -1
+	var err error
+	if c.Owner != "" {
+		uid, err = utils.LookupUid(c.Owner)
+		if err != nil {
+			return utils.Wrapf(err, "Cannot find user %s", c.Owner)
+		}
+	}
+	if c.Group != "" {
+		gid, err = utils.LookupGid(c.Group)
+		if err != nil {
+			return utils.Wrapf(err, "Cannot find group %s", c.Group)
+		}
+	}
+	err = lw.fs.Chown(name, uid, gid)
+	if err != nil {
+		return utils.Wrapf(err, "Cannot change owner of %s", name)
+	}
+	return nil
+}
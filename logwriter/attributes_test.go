@@ -0,0 +1,60 @@
+package logwriter
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertAttributes(t *testing.T, fs *fsys.MemFileSystem, name string, mode os.FileMode, uid int, gid int) {
+	info, err := fs.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, mode, info.Mode(), name)
+	u, g, err := fs.Owner(name)
+	assert.NoError(t, err)
+	assert.Equal(t, uid, u, name)
+	assert.Equal(t, gid, g, name)
+}
+
+func TestAttributesAreAppliedToLogFilesAndArchives(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	config := &state.Config{Interval: time.Hour, Suffix: "%c", FileMode: 0640, Owner: "1000", Group: "4"}
+	lw := archivingWriter(t, fs, newFakeClock(0), config)
+	defer lw.Close()
+	assertAttributes(t, fs, "/logs/file.log", 0640, 1000, 4)
+	fs.Chmod("/logs/file.log", 0600)
+	_, err := lw.Write([]byte("bar"))
+	assert.NoError(t, err)
+	assertAttributes(t, fs, "/logs/file.1.log", 0640, 1000, 4)
+	assertAttributes(t, fs, "/logs/file.log", 0640, 1000, 4)
+}
+
+func TestAttributesOfExistingLogFilesAreKept(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	fs.WriteFile("/logs/file.log", []byte("foo"), 0600)
+	config := &state.Config{Interval: time.Hour, Suffix: "%c", FileMode: 0640}
+	lw := newMemWriter(t, fs, newFakeClock(0), WithConfig(config))
+	defer lw.Close()
+	_, err := lw.Write([]byte("bar"))
+	assert.NoError(t, err)
+	assertAttributes(t, fs, "/logs/file.log", 0600, 0, 0)
+}
+
+func TestAttributesFailureIsReported(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	var errs []error
+	config := &state.Config{Interval: time.Hour, Suffix: "%c", Owner: "no-such-user-here"}
+	lw := newMemWriter(t, fs, newFakeClock(0), WithConfig(config), WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	defer lw.Close()
+	_, err := lw.Write([]byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(errs))
+	b, err := fs.ReadFile("/logs/file.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(b))
+}
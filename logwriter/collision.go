@@ -0,0 +1,80 @@
+package logwriter
+
+import (
+	"errors"
+	"os"
+
+	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+var ErrArchiveExists = errors.New("Archive already exists")
+
+func (lw *LogWriter) exists(name string) bool {
+	return utils.ExistsIn(lw.fs, name)
+}
+
+func stagingName(archive string) string {
+	return archive + ".merge"
+}
+
+// resolveCollision applies the collision policy when the rotated file already
+// exists, returning the name of the archive and whether the log file must be
+// appended to it.
+func (lw *LogWriter) resolveCollision(rotated string) (string, bool, error) {
+	if !lw.exists(rotated) {
+		return rotated, false, nil
+	}
+	switch lw.state.Config.Collision {
+	case state.CollisionAppend:
+		return rotated, true, nil
+	case state.CollisionFail:
+		return "", false, utils.Wrapf(ErrArchiveExists, "Cannot rotate to %s", rotated)
+	}
+	return filename.Uniquify(rotated, lw.exists), false, nil
+}
+
+func (lw *LogWriter) merge(staging string, archive string) error {
+	b, err := lw.fs.ReadFile(staging)
+	if err != nil {
+		return err
+	}
+	f, err := lw.fs.OpenFile(archive, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return lw.fs.Remove(staging)
+}
+
+// archive moves the log file to the archive. When merging, the log file is
+// first renamed to a staging file, so that a merge interrupted by a crash can
+// be completed by reconcile.
+func (lw *LogWriter) archive(r *state.Rotation) error {
+	if r.Shift {
+		return lw.shift(r)
+	}
+	if !r.Merge {
+		return lw.move(r.From, r.To)
+	}
+	staging := stagingName(r.To)
+	err := lw.move(r.From, staging)
+	if err != nil {
+		return err
+	}
+	err = lw.merge(staging, r.To)
+	if err != nil {
+		lw.move(staging, r.From)
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package logwriter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func collidingWriter(t *testing.T, fs *fsys.MemFileSystem, clock *fakeClock, collision string) *LogWriter {
+	config := &state.Config{Interval: time.Hour, Suffix: "%Y%m%d", Collision: collision, UTC: true}
+	lw := newMemWriter(t, fs, clock, WithConfig(config))
+	fs.WriteFile("/logs/file.19700101.log", []byte("old\n"), 0644)
+	_, err := lw.Write([]byte("foo\n"))
+	assert.NoError(t, err)
+	clock.now = clock.now.Add(time.Hour * 2)
+	return lw
+}
+
+func TestCollisionUniquifiesTheArchiveByDefault(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	lw := collidingWriter(t, fs, newFakeClock(0), "")
+	defer lw.Close()
+	_, err := lw.Write([]byte("bar\n"))
+	assert.NoError(t, err)
+	for name, content := range map[string]string{
+		"/logs/file.19700101.log":   "old\n",
+		"/logs/file.19700101-1.log": "foo\n",
+		"/logs/file.log":            "bar\n",
+	} {
+		b, err := fs.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+}
+
+func TestCollisionAppendsToTheArchive(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	lw := collidingWriter(t, fs, newFakeClock(0), state.CollisionAppend)
+	defer lw.Close()
+	_, err := lw.Write([]byte("bar\n"))
+	assert.NoError(t, err)
+	b, err := fs.ReadFile("/logs/file.19700101.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "old\nfoo\n", string(b))
+	b, err = fs.ReadFile("/logs/file.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar\n", string(b))
+	assert.False(t, lw.exists(stagingName("/logs/file.19700101.log")))
+	assert.Equal(t, 1, lw.state.Counter)
+}
+
+func TestCollisionFails(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	lw := collidingWriter(t, fs, newFakeClock(0), state.CollisionFail)
+	defer lw.Close()
+	_, err := lw.Write([]byte("bar\n"))
+	assert.True(t, errors.Is(err, ErrArchiveExists))
+	b, err := fs.ReadFile("/logs/file.19700101.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "old\n", string(b))
+	b, err = fs.ReadFile("/logs/file.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\n", string(b))
+	assert.Equal(t, 0, lw.state.Counter)
+}
+
+func TestReconcileCompletesAnInterruptedMerge(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	storage := state.NewMapStorage()
+	s := pendingState(fs)
+	s.Pending.Merge = true
+	storage.Store(s)
+	fs.WriteFile("/logs/file.2.log", []byte("old\n"), 0644)
+	fs.WriteFile(stagingName("/logs/file.2.log"), []byte("foo\n"), 0644)
+	lw, err := New("/logs/file.log", WithFileSystem(fs), WithStateStorage(storage))
+	assert.NoError(t, err)
+	b, err := fs.ReadFile("/logs/file.2.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "old\nfoo\n", string(b))
+	assert.False(t, lw.exists(stagingName("/logs/file.2.log")))
+	assert.Equal(t, 2, lw.state.Counter)
+	assert.Nil(t, lw.state.Pending)
+}
+
+func TestReconcileRollsBackAMergeNotStarted(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	storage := state.NewMapStorage()
+	s := pendingState(fs)
+	s.Pending.Merge = true
+	storage.Store(s)
+	fs.WriteFile("/logs/file.2.log", []byte("old\n"), 0644)
+	fs.WriteFile("/logs/file.log", []byte("foo\n"), 0644)
+	lw, err := New("/logs/file.log", WithFileSystem(fs), WithStateStorage(storage))
+	assert.NoError(t, err)
+	b, err := fs.ReadFile("/logs/file.2.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "old\n", string(b))
+	assert.Equal(t, 1, lw.state.Counter)
+}
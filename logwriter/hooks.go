@@ -0,0 +1,53 @@
+package logwriter
+
+import "github.com/lorenzobenvenuti/loco/state"
+
+type OpenEvent struct {
+	Path  string
+	State state.State
+}
+
+// RotateEvent is fired after the log file has been rotated; NewPath is empty if
+// there was no log file to archive.
+type RotateEvent struct {
+	OldPath string
+	NewPath string
+	State   state.State
+}
+
+// Hooks are invoked synchronously by the goroutine calling Write, while the
+// writer's lock is held: concurrent writes are blocked until a hook returns and
+// hooks must not call methods of the same LogWriter. Long running work (e.g.
+// uploading an archive) should be moved to another goroutine. States are
+// snapshots and can be retained.
+type Hooks struct {
+	OnOpen   func(e OpenEvent)
+	OnRotate func(e RotateEvent)
+	OnError  func(err error)
+}
+
+func (h *Hooks) open(path string, s *state.State) {
+	if h.OnOpen != nil {
+		h.OnOpen(OpenEvent{Path: path, State: *s})
+	}
+}
+
+func (h *Hooks) rotate(oldPath string, newPath string, s *state.State) {
+	if h.OnRotate != nil {
+		h.OnRotate(RotateEvent{OldPath: oldPath, NewPath: newPath, State: *s})
+	}
+}
+
+func (h *Hooks) error(err error) {
+	if h.OnError != nil {
+		h.OnError(err)
+	}
+}
+
+// WithHooks sets the callbacks invoked when the log file is opened or rotated
+// and on errors.
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
@@ -0,0 +1,101 @@
+package logwriter
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedEvents struct {
+	opens   []OpenEvent
+	rotates []RotateEvent
+	errors  []error
+}
+
+func (r *recordedEvents) hooks() Hooks {
+	return Hooks{
+		OnOpen:   func(e OpenEvent) { r.opens = append(r.opens, e) },
+		OnRotate: func(e RotateEvent) { r.rotates = append(r.rotates, e) },
+		OnError:  func(err error) { r.errors = append(r.errors, err) },
+	}
+}
+
+func TestHooksOnOpenAndRotate(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	fullpath := path.Join(dir, "file.log")
+	clock := newFakeClock(int64(time.Hour))
+	events := &recordedEvents{}
+	lw, err := New(
+		fullpath,
+		WithStateStorage(state.NewMapStorage()),
+		WithClock(clock),
+		WithFileNameGenerator(newFakeFileNameGenerator()),
+		WithConfig(state.NewConfig(time.Hour*24, "%c")),
+		WithHooks(events.hooks()),
+	)
+	assert.NoError(t, err)
+	defer lw.Close()
+	_, err = lw.Write([]byte("foo"))
+	assert.NoError(t, err)
+	clock.now = time.Unix(0, int64(time.Hour*26))
+	_, err = lw.Write([]byte("bar"))
+	assert.NoError(t, err)
+	assert.Equal(t, []OpenEvent{{
+		Path: fullpath,
+		State: state.State{
+			FullName:  fullpath,
+			CreatedAt: time.Unix(0, int64(time.Hour)),
+			RotatedAt: time.Unix(0, int64(time.Hour)),
+			Config:    state.Config{Interval: time.Hour * 24, Suffix: "%c"},
+		},
+	}}, events.opens)
+	assert.Equal(t, []RotateEvent{{
+		OldPath: fullpath,
+		NewPath: fullpath + ".bak",
+		State: state.State{
+			FullName:  fullpath,
+			CreatedAt: time.Unix(0, int64(time.Hour)),
+			RotatedAt: time.Unix(0, int64(time.Hour*26)),
+			Counter:   1,
+			Config:    state.Config{Interval: time.Hour * 24, Suffix: "%c"},
+		},
+	}}, events.rotates)
+	assert.Empty(t, events.errors)
+	bytes, err := ioutil.ReadFile(events.rotates[0].NewPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), bytes)
+}
+
+func TestHooksOnError(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	fullpath := path.Join(dir, "file.log")
+	storage := state.NewMapStorage()
+	storage.Store(&state.State{
+		FullName: fullpath,
+		Config:   state.Config{Interval: time.Hour, Suffix: "%c"},
+	})
+	events := &recordedEvents{}
+	var handled []error
+	lw, err := New(
+		fullpath,
+		WithStateStorage(&failingStorage{storage}),
+		WithClock(newFakeClock(42)),
+		WithErrorHandler(func(err error) { handled = append(handled, err) }),
+		WithHooks(events.hooks()),
+	)
+	assert.NoError(t, err)
+	defer lw.Close()
+	_, err = lw.Write([]byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, []error{errors.New("Storage is broken")}, events.errors)
+	assert.Equal(t, events.errors, handled)
+}
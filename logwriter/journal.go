@@ -0,0 +1,91 @@
+package logwriter
+
+import (
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// isRotated tells whether the log file is still the one recorded by r, rather
+// than a log file created after the rotation. Rotations recorded without the
+// identity of the file are assumed to refer to any log file.
+func (lw *LogWriter) isRotated(r *state.Rotation) bool {
+	info, err := lw.fs.Stat(r.From)
+	if err != nil {
+		return false
+	}
+	if r.ModTime.IsZero() {
+		return true
+	}
+	return info.Size() == r.Size && info.ModTime().Equal(r.ModTime)
+}
+
+// dropPending clears a rotation that was already completed or rolled back by a
+// writer that couldn't store the outcome, and then kept writing to a new log
+// file.
+func (lw *LogWriter) dropPending(p *state.Rotation) error {
+	if lw.exists(p.To) && !lw.exists(stagingName(p.To)) {
+		lw.state.Counter = p.Counter
+		lw.state.RotatedAt = p.RotatedAt
+	}
+	lw.state.Pending = nil
+	err := lw.stateStorage.Store(lw.state)
+	if err != nil {
+		return utils.Wrap(err, "Cannot store reconciled state")
+	}
+	return nil
+}
+
+// reconcile resolves a rotation interrupted by a crash. If the log file has
+// been renamed the rotation is completed, otherwise it's rolled back and it will
+// be performed again by the next write. An interrupted shift is resumed, while
+// an interrupted merge is completed again, so part of the merged file may be
+// duplicated. If the log file isn't the rotated one, the rotation had already
+// ended and only its outcome is stored.
+func (lw *LogWriter) reconcile() error {
+	p := lw.state.Pending
+	if p == nil {
+		return nil
+	}
+	if lw.exists(p.From) && !lw.isRotated(p) {
+		return lw.dropPending(p)
+	}
+	if p.Shift {
+		err := lw.shift(p)
+		if err != nil {
+			return utils.Wrap(err, "Cannot complete shift")
+		}
+	}
+	if !p.Shift {
+		target := p.To
+		if p.Merge {
+			target = stagingName(p.To)
+		}
+		err := lw.finishMove(p, target)
+		if err != nil {
+			return utils.Wrap(err, "Cannot complete move")
+		}
+	}
+	if p.Merge && lw.exists(stagingName(p.To)) {
+		err := lw.merge(stagingName(p.To), p.To)
+		if err != nil {
+			return utils.Wrap(err, "Cannot complete merge")
+		}
+	}
+	archived := lw.exists(p.To) && (!p.Merge || !lw.exists(p.From))
+	completed := archived || !lw.exists(p.From)
+	if completed {
+		lw.state.Counter = p.Counter
+		lw.state.RotatedAt = p.RotatedAt
+	}
+	lw.state.Pending = nil
+	err := lw.stateStorage.Store(lw.state)
+	if err != nil {
+		return utils.Wrap(err, "Cannot store reconciled state")
+	}
+	if archived {
+		lw.hooks.rotate(p.From, p.To, lw.state)
+	} else if completed {
+		lw.hooks.rotate(p.From, "", lw.state)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package logwriter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingStorage struct {
+	state.StateStorage
+	stored []state.State
+}
+
+func (s *recordingStorage) Store(st *state.State) error {
+	s.stored = append(s.stored, *st)
+	return s.StateStorage.Store(st)
+}
+
+func pendingState(fs *fsys.MemFileSystem) *state.State {
+	fs.MkdirAll("/logs", 0755)
+	return &state.State{
+		FullName:  "/logs/file.log",
+		CreatedAt: time.Unix(0, 0),
+		RotatedAt: time.Unix(0, 0),
+		Counter:   1,
+		Config:    state.Config{Interval: time.Hour, Suffix: "%c"},
+		Pending: &state.Rotation{
+			From:      "/logs/file.log",
+			To:        "/logs/file.2.log",
+			Counter:   2,
+			RotatedAt: time.Unix(7200, 0),
+		},
+	}
+}
+
+type flakyStorage struct {
+	state.StateStorage
+	calls  int
+	failAt int
+}
+
+func (s *flakyStorage) Store(st *state.State) error {
+	s.calls++
+	if s.calls == s.failAt {
+		return errors.New("Storage is broken")
+	}
+	return s.StateStorage.Store(st)
+}
+
+func TestRotationIsRecordedBeforeRenaming(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.SetClock(func() time.Time { return time.Unix(60, 0) })
+	clock := newFakeClock(0)
+	storage := &recordingStorage{StateStorage: state.NewMapStorage()}
+	lw := newMemWriter(t, fs, clock, WithStateStorage(storage))
+	defer lw.Close()
+	lw.Write([]byte("foo"))
+	clock.now = time.Unix(7200, 0)
+	storage.stored = nil
+	_, err := lw.Write([]byte("bar"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(storage.stored))
+	assert.Equal(t, &state.Rotation{
+		From:      "/logs/file.log",
+		To:        "/logs/file.1.log",
+		Counter:   1,
+		RotatedAt: time.Unix(7200, 0),
+		Size:      3,
+		ModTime:   time.Unix(60, 0),
+	}, storage.stored[0].Pending)
+	assert.Equal(t, 0, storage.stored[0].Counter)
+	assert.Nil(t, storage.stored[1].Pending)
+	assert.Equal(t, 1, storage.stored[1].Counter)
+}
+
+func TestRotationIsNotPerformedIfTheIntentCannotBeRecorded(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	storage := state.NewMapStorage()
+	var errs []error
+	lw := newMemWriter(t, fs, clock, WithStateStorage(storage), WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	defer lw.Close()
+	lw.Write([]byte("foo"))
+	lw.stateStorage = &failingStorage{storage}
+	clock.now = time.Unix(7200, 0)
+	_, err := lw.Write([]byte("bar"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(errs))
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.1.log"))
+	assert.Nil(t, lw.state.Pending)
+	assert.Equal(t, 0, lw.state.Counter)
+}
+
+func TestReconcileCompletesARenamedRotation(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	storage := state.NewMapStorage()
+	storage.Store(pendingState(fs))
+	fs.WriteFile("/logs/file.2.log", []byte("foo"), 0644)
+	var events []RotateEvent
+	lw, err := New(
+		"/logs/file.log",
+		WithFileSystem(fs),
+		WithStateStorage(storage),
+		WithHooks(Hooks{OnRotate: func(e RotateEvent) { events = append(events, e) }}),
+	)
+	assert.NoError(t, err)
+	expected := &state.State{
+		FullName:  "/logs/file.log",
+		CreatedAt: time.Unix(0, 0),
+		RotatedAt: time.Unix(7200, 0),
+		Counter:   2,
+		Config:    state.Config{Interval: time.Hour, Suffix: "%c"},
+	}
+	assert.Equal(t, expected, lw.state)
+	stored, err := storage.Load("/logs/file.log")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, stored)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "/logs/file.2.log", events[0].NewPath)
+}
+
+func TestReconcileRollsBackARotationNotRenamed(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	storage := state.NewMapStorage()
+	storage.Store(pendingState(fs))
+	fs.WriteFile("/logs/file.log", []byte("foo"), 0644)
+	clock := newFakeClock(int64(time.Hour * 3))
+	lw, err := New(
+		"/logs/file.log",
+		WithFileSystem(fs),
+		WithStateStorage(storage),
+		WithClock(clock),
+		WithFileNameGenerator(filename.NewFileNameGenerator()),
+	)
+	assert.NoError(t, err)
+	defer lw.Close()
+	expected := &state.State{
+		FullName:  "/logs/file.log",
+		CreatedAt: time.Unix(0, 0),
+		RotatedAt: time.Unix(0, 0),
+		Counter:   1,
+		Config:    state.Config{Interval: time.Hour, Suffix: "%c"},
+	}
+	assert.Equal(t, expected, lw.state)
+	_, err = lw.Write([]byte("bar"))
+	assert.NoError(t, err)
+	b, err := fs.ReadFile("/logs/file.2.log")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), b)
+	assert.Equal(t, 2, lw.state.Counter)
+}
+
+func TestRotateReportsAStateNotStoredAfterRenaming(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	storage := &flakyStorage{StateStorage: state.NewMapStorage()}
+	lw := newMemWriter(t, fs, newFakeClock(0), WithStateStorage(storage))
+	lw.Write([]byte("foo"))
+	storage.failAt = storage.calls + 2
+	assert.Error(t, lw.Rotate())
+	lw.Write([]byte("bar"))
+	assert.NoError(t, lw.Close())
+	lw, err := New("/logs/file.log", WithFileSystem(fs), WithStateStorage(storage))
+	assert.NoError(t, err)
+	assert.Nil(t, lw.state.Pending)
+	assert.Equal(t, 1, lw.state.Counter)
+	for name, content := range map[string]string{
+		"/logs/file.log":   "bar",
+		"/logs/file.1.log": "foo",
+	} {
+		b, err := fs.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+}
+
+func TestReconcileFailsIfTheStateCannotBeStored(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	storage := state.NewMapStorage()
+	storage.Store(pendingState(fs))
+	_, err := New("/logs/file.log", WithFileSystem(fs), WithStateStorage(&failingStorage{storage}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,35 @@
+package logwriter
+
+import (
+	"os"
+
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+func (lw *LogWriter) dirMode() os.FileMode {
+	if lw.state.Config.DirMode != 0 {
+		return lw.state.Config.DirMode
+	}
+	return state.DefaultDirMode
+}
+
+// move renames from to to, creating the directory of to if needed and copying
+// the file when to is on another file system.
+func (lw *LogWriter) move(from string, to string) error {
+	return utils.Move(lw.fs, from, to, lw.dirMode())
+}
+
+// finishMove completes the move of the file rotated by r to to, when it was
+// interrupted while copying across file systems. A leftover part file means
+// the copy wasn't completed, so it's discarded; otherwise the source is removed
+// if it's still the file that was copied.
+func (lw *LogWriter) finishMove(r *state.Rotation, to string) error {
+	if lw.exists(utils.PartName(to)) {
+		return lw.fs.Remove(utils.PartName(to))
+	}
+	if lw.exists(to) && lw.isRotated(r) {
+		return lw.fs.Remove(r.From)
+	}
+	return nil
+}
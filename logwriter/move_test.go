@@ -0,0 +1,114 @@
+package logwriter
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func archivingWriter(t *testing.T, fs *fsys.MemFileSystem, clock *fakeClock, config *state.Config) *LogWriter {
+	lw := newMemWriter(t, fs, clock, WithConfig(config))
+	_, err := lw.Write([]byte("foo"))
+	assert.NoError(t, err)
+	clock.now = clock.now.Add(time.Hour * 2)
+	return lw
+}
+
+func TestArchiveDirIsCreatedWithTheConfiguredMode(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	config := &state.Config{Interval: time.Hour, Suffix: "%c", ArchiveDir: "/archive/%Y/%m", DirMode: 0750, UTC: true}
+	lw := archivingWriter(t, fs, newFakeClock(0), config)
+	defer lw.Close()
+	_, err := lw.Write([]byte("bar"))
+	assert.NoError(t, err)
+	b, err := fs.ReadFile("/archive/1970/01/file.1.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(b))
+	info, err := fs.Stat("/archive/1970/01")
+	assert.NoError(t, err)
+	assert.Equal(t, "drwxr-x---", info.Mode().String())
+}
+
+func TestArchiveDirIsRelativeToTheLogFile(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	config := &state.Config{Interval: time.Hour, Suffix: "%c", ArchiveDir: "old"}
+	lw := archivingWriter(t, fs, newFakeClock(0), config)
+	defer lw.Close()
+	_, err := lw.Write([]byte("bar"))
+	assert.NoError(t, err)
+	assert.True(t, utils.ExistsIn(fs, "/logs/old/file.1.log"))
+}
+
+func TestArchiveIsCopiedAcrossFileSystems(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	config := &state.Config{Interval: time.Hour, Suffix: "%c", ArchiveDir: "/archive"}
+	lw := archivingWriter(t, fs, newFakeClock(0), config)
+	defer lw.Close()
+	fs.InjectFault(fsys.OpRename, "/logs/file.log", syscall.EXDEV)
+	_, err := lw.Write([]byte("bar"))
+	assert.NoError(t, err)
+	b, err := fs.ReadFile("/archive/file.1.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(b))
+	b, err = fs.ReadFile("/logs/file.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", string(b))
+	assert.False(t, utils.ExistsIn(fs, "/archive/file.1.log.part"))
+	assert.Equal(t, 1, lw.state.Counter)
+}
+
+func TestShiftUsesTheArchiveDir(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	config := &state.Config{Interval: time.Hour, Naming: state.NamingShift, ArchiveDir: "/archive"}
+	lw := newMemWriter(t, fs, clock, WithConfig(config))
+	writeHourly(t, lw, clock, 3)
+	assert.NoError(t, lw.Close())
+	b, err := fs.ReadFile("/archive/file.log.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "line 0", string(b))
+}
+
+func TestReconcileCompletesAnInterruptedCopy(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	storage := state.NewMapStorage()
+	storage.Store(pendingState(fs))
+	fs.WriteFile("/logs/file.log", []byte("foo"), 0644)
+	fs.WriteFile("/logs/file.2.log", []byte("foo"), 0644)
+	lw, err := New("/logs/file.log", WithFileSystem(fs), WithStateStorage(storage))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, lw.state.Counter)
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.log"))
+}
+
+func TestReconcileDiscardsAnIncompleteCopy(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	storage := state.NewMapStorage()
+	storage.Store(pendingState(fs))
+	fs.WriteFile("/logs/file.log", []byte("foo"), 0644)
+	fs.WriteFile("/logs/file.2.log.part", []byte("f"), 0644)
+	lw, err := New("/logs/file.log", WithFileSystem(fs), WithStateStorage(storage))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, lw.state.Counter)
+	assert.True(t, utils.ExistsIn(fs, "/logs/file.log"))
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.2.log.part"))
+}
+
+func TestFinishMoveKeepsALogFileCreatedAfterTheCopy(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	lw := newMemWriter(t, fs, newFakeClock(0))
+	fs.WriteFile("/logs/file.log", []byte("bar"), 0644)
+	fs.WriteFile("/logs/file.1.log", []byte("foo"), 0644)
+	r := &state.Rotation{From: "/logs/file.log", To: "/logs/file.1.log", Size: 3, ModTime: time.Unix(60, 0)}
+	assert.NoError(t, lw.finishMove(r, r.To))
+	assert.True(t, utils.ExistsIn(fs, "/logs/file.log"))
+	fs.SetClock(func() time.Time { return time.Unix(60, 0) })
+	fs.WriteFile("/logs/file.log", []byte("foo"), 0644)
+	assert.NoError(t, lw.finishMove(r, r.To))
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.log"))
+}
@@ -0,0 +1,116 @@
+package logwriter
+
+import (
+	"errors"
+	"os"
+
+	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+type options struct {
+	fs                fsys.FileSystem
+	storage           state.StateStorage
+	clock             Clock
+	fileNameGenerator filename.FileNameGenerator
+	config            *state.Config
+	errorHandler      func(error)
+	hooks             Hooks
+}
+
+type Option func(*options)
+
+// WithStateStorage sets the storage used to load and persist the state of the
+// log file. The default is the storage in the user's home directory.
+func WithStateStorage(storage state.StateStorage) Option {
+	return func(o *options) {
+		o.storage = storage
+	}
+}
+
+// WithFileSystem sets the file system where log files are written. The default
+// is the OS file system.
+func WithFileSystem(fs fsys.FileSystem) Option {
+	return func(o *options) {
+		o.fs = fs
+	}
+}
+
+// WithClock sets the clock used to decide when to rotate. The default is the
+// system clock.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithFileNameGenerator sets the generator of the names of rotated files. The
+// default renders the configured suffix in local time.
+func WithFileNameGenerator(generator filename.FileNameGenerator) Option {
+	return func(o *options) {
+		o.fileNameGenerator = generator
+	}
+}
+
+// WithConfig sets the configuration of the log file. If a state already exists
+// its configuration is replaced, otherwise a new state is created.
+func WithConfig(config *state.Config) Option {
+	return func(o *options) {
+		o.config = config
+	}
+}
+
+// WithErrorHandler sets a function that receives the errors that cannot be
+// returned by Write, e.g. failures while rotating or storing the state.
+func WithErrorHandler(handler func(error)) Option {
+	return func(o *options) {
+		o.errorHandler = handler
+	}
+}
+
+// New returns a LogWriter for the given file. The state is loaded from the
+// storage; if it doesn't exist a configuration must be provided with WithConfig.
+func New(fullName string, opts ...Option) (*LogWriter, error) {
+	o := &options{
+		fs:                fsys.NewOSFileSystem(),
+		clock:             defaultClock,
+		fileNameGenerator: fileNameGenerator,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.storage == nil {
+		storage, err := state.NewHomeDirStateStorage()
+		if err != nil {
+			return nil, err
+		}
+		o.storage = storage
+	}
+	s, err := o.storage.Load(fullName)
+	if err != nil {
+		if o.config == nil || !errors.Is(err, os.ErrNotExist) {
+			return nil, utils.Wrapf(err, "Cannot load state of %s", fullName)
+		}
+		s, err = state.NewState(o.storage, fullName, *o.config)
+		if err != nil {
+			return nil, utils.Wrap(err, "Cannot store state")
+		}
+	} else if o.config != nil {
+		s.Config = *o.config
+		err = o.storage.Store(s)
+		if err != nil {
+			return nil, utils.Wrap(err, "Cannot store state")
+		}
+	}
+	lw := writer(s, o.storage, o.clock, o.fileNameGenerator)
+	lw.fs = o.fs
+	lw.errorHandler = o.errorHandler
+	lw.hooks = o.hooks
+	err = lw.reconcile()
+	if err != nil {
+		return nil, err
+	}
+	return lw, nil
+}
@@ -0,0 +1,103 @@
+package logwriter
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFailsWithoutStateAndConfig(t *testing.T) {
+	_, err := New("/path/to/file", WithStateStorage(state.NewMapStorage()))
+	assert.Error(t, err)
+}
+
+func TestNewCreatesAStateFromConfig(t *testing.T) {
+	storage := state.NewMapStorage()
+	config := state.NewConfig(time.Hour, "%c")
+	lw, err := New("/path/to/file", WithStateStorage(storage), WithConfig(config))
+	assert.NoError(t, err)
+	s, err := storage.Load("/path/to/file")
+	assert.NoError(t, err)
+	assert.Equal(t, s, lw.state)
+	assert.Equal(t, *config, s.Config)
+}
+
+func TestNewLoadsAnExistingState(t *testing.T) {
+	storage := state.NewMapStorage()
+	s := &state.State{
+		FullName:  "/path/to/file",
+		CreatedAt: time.Unix(0, 12),
+		RotatedAt: time.Unix(0, 34),
+		Counter:   3,
+		Config:    state.Config{Interval: time.Hour * 24, Suffix: "%c"},
+	}
+	storage.Store(s)
+	lw, err := New("/path/to/file", WithStateStorage(storage), WithConfig(state.NewConfig(time.Hour, "%Y")))
+	assert.NoError(t, err)
+	expected := &state.State{
+		FullName:  "/path/to/file",
+		CreatedAt: time.Unix(0, 12),
+		RotatedAt: time.Unix(0, 34),
+		Counter:   3,
+		Config:    state.Config{Interval: time.Hour, Suffix: "%Y"},
+	}
+	assert.Equal(t, expected, lw.state)
+}
+
+type failingStorage struct {
+	state.StateStorage
+}
+
+func (s *failingStorage) Store(state *state.State) error {
+	return errors.New("Storage is broken")
+}
+
+type unreadableStorage struct {
+	state.StateStorage
+}
+
+func (s *unreadableStorage) Load(fullName string) (*state.State, error) {
+	return nil, os.ErrPermission
+}
+
+func TestNewDoesNotReplaceAnUnreadableState(t *testing.T) {
+	storage := &unreadableStorage{state.NewMapStorage()}
+	_, err := New("/path/to/file", WithStateStorage(storage), WithConfig(state.NewConfig(time.Hour, "%c")))
+	assert.True(t, errors.Is(err, os.ErrPermission))
+	_, err = storage.StateStorage.Load("/path/to/file")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestNewPassesErrorsToTheErrorHandler(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	fullpath := path.Join(dir, "file.log")
+	storage := state.NewMapStorage()
+	storage.Store(&state.State{
+		FullName: fullpath,
+		Config:   state.Config{Interval: time.Hour, Suffix: "%c"},
+	})
+	var errs []error
+	lw, err := New(
+		fullpath,
+		WithStateStorage(&failingStorage{storage}),
+		WithClock(newFakeClock(42)),
+		WithFileNameGenerator(newFakeFileNameGenerator()),
+		WithErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	assert.NoError(t, err)
+	defer lw.Close()
+	_, err = lw.Write([]byte("foo"))
+	assert.NoError(t, err)
+	bytes, err := ioutil.ReadFile(fullpath)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), bytes)
+	assert.Equal(t, 1, len(errs))
+}
@@ -0,0 +1,46 @@
+package logwriter
+
+import (
+	"os"
+
+	"github.com/lorenzobenvenuti/loco/archives"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// generated tells whether an archive could have been written by this host: a
+// name matched by the hostname of another host belongs to another writer or
+// isn't an archive at all.
+func generated(a *archives.Archive, hostname string) bool {
+	return a.Match.Host == "" || a.Match.Host == hostname
+}
+
+// pruneArchives removes the oldest archives beyond the retention; shifted
+// archives are pruned while shifting.
+func (lw *LogWriter) pruneArchives() error {
+	retention := lw.state.Config.Retention
+	if retention <= 0 || lw.state.Config.Naming == state.NamingShift {
+		return nil
+	}
+	all, err := archives.List(lw.fs, lw.state)
+	if err != nil {
+		return err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	list := make([]*archives.Archive, 0, len(all))
+	for _, a := range all {
+		if generated(a, hostname) {
+			list = append(list, a)
+		}
+	}
+	for i := 0; i < len(list)-retention; i++ {
+		err := lw.fs.Remove(list[i].Path)
+		if err != nil {
+			return utils.Wrap(err, "Cannot remove old archive")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package logwriter
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionRemovesTheOldestArchives(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	config := &state.Config{Interval: time.Hour, Suffix: "%c", Retention: 2}
+	lw := newMemWriter(t, fs, clock, WithConfig(config), WithFileNameGenerator(filename.NewFileNameGenerator()))
+	writeHourly(t, lw, clock, 5)
+	assert.NoError(t, lw.Close())
+	for name, exists := range map[string]bool{
+		"/logs/file.1.log": false,
+		"/logs/file.2.log": false,
+		"/logs/file.3.log": true,
+		"/logs/file.4.log": true,
+		"/logs/file.log":   true,
+	} {
+		assert.Equal(t, exists, utils.ExistsIn(fs, name), name)
+	}
+}
+
+func TestRetentionKeepsFilesOfOtherHosts(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	config := &state.Config{Interval: time.Hour, Suffix: "%h", Retention: 1}
+	lw := newMemWriter(t, fs, clock, WithConfig(config), WithFileNameGenerator(filename.NewFileNameGenerator()))
+	fs.WriteFile("/logs/file.foo.log", []byte("foreign"), 0644)
+	writeHourly(t, lw, clock, 3)
+	assert.NoError(t, lw.Close())
+	hostname, err := os.Hostname()
+	assert.NoError(t, err)
+	assert.True(t, utils.ExistsIn(fs, "/logs/file.foo.log"))
+	assert.False(t, utils.ExistsIn(fs, "/logs/file."+hostname+".log"))
+	assert.True(t, utils.ExistsIn(fs, "/logs/file."+hostname+"-1.log"))
+}
@@ -0,0 +1,49 @@
+package logwriter
+
+import (
+	"strings"
+
+	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// shift renames file.N to file.N+1, starting from the first missing archive,
+// and then moves the log file to file.1. Since no archive is ever overwritten,
+// an interrupted shift is completed by running it again; archives are shifted
+// only while the log file is still the one recorded by r.
+func (lw *LogWriter) shift(r *state.Rotation) error {
+	base := strings.TrimSuffix(r.To, ".1")
+	if lw.isRotated(r) {
+		free := 1
+		for lw.exists(filename.ShiftedFileName(base, free)) {
+			free++
+		}
+		for i := free; i > 1; i-- {
+			from, to := filename.ShiftedFileName(base, i-1), filename.ShiftedFileName(base, i)
+			err := lw.fs.Rename(from, to)
+			if err != nil {
+				return utils.Wrapf(err, "Cannot shift %s", from)
+			}
+		}
+		err := lw.move(r.From, r.To)
+		if err != nil {
+			return err
+		}
+	}
+	return lw.pruneShifted(base)
+}
+
+func (lw *LogWriter) pruneShifted(base string) error {
+	retention := lw.state.Config.Retention
+	if retention <= 0 {
+		return nil
+	}
+	for i := retention + 1; lw.exists(filename.ShiftedFileName(base, i)); i++ {
+		err := lw.fs.Remove(filename.ShiftedFileName(base, i))
+		if err != nil {
+			return utils.Wrap(err, "Cannot remove old archive")
+		}
+	}
+	return nil
+}
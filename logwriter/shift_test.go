@@ -0,0 +1,148 @@
+package logwriter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func shiftingWriter(t *testing.T, fs *fsys.MemFileSystem, clock *fakeClock, retention int) *LogWriter {
+	config := &state.Config{Interval: time.Hour, Suffix: "%c", Naming: state.NamingShift, Retention: retention}
+	return newMemWriter(t, fs, clock, WithConfig(config))
+}
+
+func writeHourly(t *testing.T, lw *LogWriter, clock *fakeClock, lines int) {
+	for i := 0; i < lines; i++ {
+		_, err := lw.Write([]byte(fmt.Sprintf("line %d", i)))
+		assert.NoError(t, err)
+		clock.now = clock.now.Add(time.Hour * 2)
+	}
+}
+
+func TestShiftRenamesArchives(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	lw := shiftingWriter(t, fs, clock, 0)
+	writeHourly(t, lw, clock, 4)
+	assert.NoError(t, lw.Close())
+	for name, content := range map[string]string{
+		"/logs/file.log":   "line 3",
+		"/logs/file.log.1": "line 2",
+		"/logs/file.log.2": "line 1",
+		"/logs/file.log.3": "line 0",
+	} {
+		b, err := fs.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+}
+
+func TestShiftRemovesArchivesBeyondRetention(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	lw := shiftingWriter(t, fs, clock, 2)
+	writeHourly(t, lw, clock, 5)
+	assert.NoError(t, lw.Close())
+	b, err := fs.ReadFile("/logs/file.log.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "line 2", string(b))
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.log.3"))
+}
+
+func TestShiftFillsTheFirstGap(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	lw := shiftingWriter(t, fs, clock, 0)
+	fs.WriteFile("/logs/file.log.1", []byte("one"), 0644)
+	fs.WriteFile("/logs/file.log.3", []byte("three"), 0644)
+	writeHourly(t, lw, clock, 2)
+	assert.NoError(t, lw.Close())
+	for name, content := range map[string]string{
+		"/logs/file.log.1": "line 0",
+		"/logs/file.log.2": "one",
+		"/logs/file.log.3": "three",
+	} {
+		b, err := fs.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+}
+
+func TestReconcileResumesAnInterruptedShift(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	storage := state.NewMapStorage()
+	st := pendingState(fs)
+	st.Config.Naming = state.NamingShift
+	st.Config.Retention = 2
+	st.Pending.To = "/logs/file.log.1"
+	st.Pending.Shift = true
+	storage.Store(st)
+	// the process died after renaming file.log.2 to file.log.3
+	fs.WriteFile("/logs/file.log", []byte("current"), 0644)
+	fs.WriteFile("/logs/file.log.1", []byte("newer"), 0644)
+	fs.WriteFile("/logs/file.log.3", []byte("older"), 0644)
+	lw, err := New("/logs/file.log", WithFileSystem(fs), WithStateStorage(storage))
+	assert.NoError(t, err)
+	assert.Nil(t, lw.state.Pending)
+	assert.Equal(t, 2, lw.state.Counter)
+	for name, content := range map[string]string{
+		"/logs/file.log.1": "current",
+		"/logs/file.log.2": "newer",
+	} {
+		b, err := fs.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.log"))
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.log.3"))
+}
+
+func TestReconcileDoesNotShiftALogFileCreatedAfterTheRotation(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	storage := &flakyStorage{StateStorage: state.NewMapStorage()}
+	config := &state.Config{Interval: time.Hour, Suffix: "%c", Naming: state.NamingShift}
+	lw := newMemWriter(t, fs, clock, WithConfig(config), WithStateStorage(storage))
+	writeHourly(t, lw, clock, 2)
+	storage.failAt = storage.calls + 2
+	writeHourly(t, lw, clock, 1)
+	assert.NoError(t, lw.Close())
+	lw, err := New("/logs/file.log", WithFileSystem(fs), WithStateStorage(storage))
+	assert.NoError(t, err)
+	assert.Nil(t, lw.state.Pending)
+	assert.Equal(t, 2, lw.state.Counter)
+	for name, content := range map[string]string{
+		"/logs/file.log":   "line 0",
+		"/logs/file.log.1": "line 1",
+		"/logs/file.log.2": "line 0",
+	} {
+		b, err := fs.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.log.3"))
+}
+
+func TestShiftFailureKeepsTheLogFile(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	var errs []error
+	config := &state.Config{Interval: time.Hour, Suffix: "%c", Naming: state.NamingShift}
+	lw := newMemWriter(t, fs, clock, WithConfig(config), WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	defer lw.Close()
+	writeHourly(t, lw, clock, 2)
+	fs.InjectFault(fsys.OpRename, "/logs/file.log.1", fmt.Errorf("boom"))
+	_, err := lw.Write([]byte("line 2"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(errs))
+	assert.Nil(t, lw.state.Pending)
+	assert.Equal(t, 1, lw.state.Counter)
+	b, err := fs.ReadFile("/logs/file.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1line 2", string(b))
+}
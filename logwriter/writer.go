@@ -1,39 +1,65 @@
 package logwriter
 
 import (
+	"errors"
 	"io"
 	"os"
+	"path"
+	"sync"
 	"time"
 
 	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/fsys"
 	"github.com/lorenzobenvenuti/loco/state"
 	"github.com/lorenzobenvenuti/loco/utils"
 )
 
-type nowProvider interface {
+type Clock interface {
 	Now() time.Time
 }
 
-type timeNowProvider struct{}
+type systemClock struct{}
 
-func (t *timeNowProvider) Now() time.Time {
+func (c *systemClock) Now() time.Time {
 	return time.Now()
 }
 
-var defaultNowProvider = &timeNowProvider{}
+var defaultClock = &systemClock{}
 
 var fileNameGenerator = filename.NewFileNameGenerator()
 
 type LogWriter struct {
+	mu                sync.Mutex
 	state             *state.State
-	file              *os.File
+	fs                fsys.FileSystem
+	file              fsys.File
 	stateStorage      state.StateStorage
-	nowProvider       nowProvider
+	clock             Clock
 	fileNameGenerator filename.FileNameGenerator
+	errorHandler      func(error)
+	hooks             Hooks
 }
 
-func (lw *LogWriter) openLogFile() (*os.File, error) {
-	return os.OpenFile(lw.state.FullName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+func (lw *LogWriter) handleError(err error) {
+	if err == nil {
+		return
+	}
+	if lw.errorHandler != nil {
+		lw.errorHandler(err)
+	}
+	lw.hooks.error(err)
+}
+
+func (lw *LogWriter) openLogFile() (fsys.File, error) {
+	created := !lw.exists(lw.state.FullName)
+	f, err := lw.fs.OpenFile(lw.state.FullName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, lw.fileMode())
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		lw.handleError(lw.setAttributes(lw.state.FullName))
+	}
+	return f, nil
 }
 
 func (lw *LogWriter) createLogFile() error {
@@ -41,45 +67,113 @@ func (lw *LogWriter) createLogFile() error {
 	if err != nil {
 		return utils.Wrap(err, "Cannot open log file")
 	}
-	lw.state.CreatedAt = lw.nowProvider.Now()
-	lw.state.RotatedAt = lw.nowProvider.Now()
-	lw.stateStorage.Store(lw.state)
+	lw.state.CreatedAt = lw.clock.Now()
+	lw.state.RotatedAt = lw.clock.Now()
+	lw.handleError(lw.stateStorage.Store(lw.state))
 	lw.file = f
+	lw.hooks.open(lw.state.FullName, lw.state)
 	return nil
 }
 
+func (lw *LogWriter) closeLogFile() error {
+	if lw.file == nil {
+		return nil
+	}
+	err := lw.file.Close()
+	lw.file = nil
+	return err
+}
+
+func (lw *LogWriter) rotation(next *state.State) (*state.Rotation, error) {
+	info, err := lw.fs.Stat(lw.state.FullName)
+	if err != nil {
+		return nil, err
+	}
+	r := &state.Rotation{
+		From:      lw.state.FullName,
+		Counter:   next.Counter,
+		RotatedAt: next.RotatedAt,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+	}
+	if lw.state.Config.Naming == state.NamingShift {
+		base := path.Join(filename.ArchiveDir(lw.fileNameGenerator, next), path.Base(lw.state.FullName))
+		r.To = filename.ShiftedFileName(base, 1)
+		r.Shift = true
+		return r, nil
+	}
+	rotated, merge, err := lw.resolveCollision(lw.fileNameGenerator.FileName(next))
+	if err != nil {
+		return nil, err
+	}
+	r.To = rotated
+	r.Merge = merge
+	return r, nil
+}
+
 func (lw *LogWriter) rotateLogFile() error {
-	err := lw.Close()
+	err := lw.closeLogFile()
 	if err != nil {
 		return utils.Wrap(err, "Error closing log writer")
 	}
-	lw.state.RotatedAt = lw.nowProvider.Now()
-	lw.state.Counter++
-	rotated := lw.fileNameGenerator.FileName(lw.state)
-	if utils.Exists(lw.state.FullName) {
-		err = os.Rename(lw.state.FullName, rotated)
+	next := *lw.state
+	next.RotatedAt = lw.clock.Now()
+	next.Counter++
+	archived := ""
+	if utils.ExistsIn(lw.fs, lw.state.FullName) {
+		lw.state.Pending, err = lw.rotation(&next)
+		if err != nil {
+			return err
+		}
+		err = lw.stateStorage.Store(lw.state)
 		if err != nil {
-			return utils.Wrapf(err, "Error renaming log file to %s", rotated)
+			lw.state.Pending = nil
+			return utils.Wrap(err, "Error recording rotation")
 		}
+		archived = lw.state.Pending.To
+		err = lw.archive(lw.state.Pending)
+		if err != nil {
+			lw.state.Pending = nil
+			lw.handleError(lw.stateStorage.Store(lw.state))
+			return utils.Wrapf(err, "Error renaming log file to %s", archived)
+		}
+		lw.handleError(lw.setAttributes(archived))
+		lw.handleError(lw.pruneArchives())
 	}
+	lw.state.Pending = nil
+	lw.state.RotatedAt = next.RotatedAt
+	lw.state.Counter = next.Counter
+	serr := lw.stateStorage.Store(lw.state)
 	f, err := lw.openLogFile()
 	if err != nil {
 		return utils.Wrap(err, "Error opening log writer")
 	}
 	lw.file = f
-	lw.stateStorage.Store(lw.state)
+	lw.hooks.rotate(lw.state.FullName, archived, lw.state)
+	if serr != nil {
+		return utils.Wrap(serr, "Error storing rotated state")
+	}
 	return nil
 }
 
+// Write appends p to the log file, rotating it if needed. It is safe to call
+// Write and Close from multiple goroutines.
 func (lw *LogWriter) Write(p []byte) (n int, err error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
 	if lw.state.FileMustBeCreated() {
 		err := lw.createLogFile()
 		if err != nil {
 			return 0, utils.Wrap(err, "Error creating log file")
 		}
-	} else if lw.state.FileMustBeRotated(lw.nowProvider.Now()) {
-		lw.rotateLogFile()
-	} else if lw.file == nil {
+	} else if lw.state.FileMustBeRotated(lw.clock.Now()) {
+		err := lw.rotateLogFile()
+		if errors.Is(err, ErrArchiveExists) {
+			return 0, err
+		}
+		lw.handleError(err)
+	}
+	if lw.file == nil {
 		f, err := lw.openLogFile()
 		if err != nil {
 			return 0, utils.Wrap(err, "Error opening log writer")
@@ -89,11 +183,33 @@ func (lw *LogWriter) Write(p []byte) (n int, err error) {
 	return lw.file.Write(p)
 }
 
-func (lw *LogWriter) Close() error {
+// Rotate archives the log file immediately, regardless of the interval, and
+// starts a new one. Nothing happens if the log file doesn't exist.
+func (lw *LogWriter) Rotate() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if !lw.exists(lw.state.FullName) {
+		return nil
+	}
+	if lw.state.FileMustBeCreated() {
+		lw.state.CreatedAt = lw.clock.Now()
+	}
+	return lw.rotateLogFile()
+}
+
+func (lw *LogWriter) Sync() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
 	if lw.file == nil {
 		return nil
 	}
-	return lw.file.Close()
+	return lw.file.Sync()
+}
+
+func (lw *LogWriter) Close() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.closeLogFile()
 }
 
 func LoadWriter(fullName string) (io.WriteCloser, error) {
@@ -101,12 +217,12 @@ func LoadWriter(fullName string) (io.WriteCloser, error) {
 	if err != nil {
 		return nil, err
 	}
-	return loadWriter(storage, defaultNowProvider, fileNameGenerator, fullName)
+	return loadWriter(storage, defaultClock, fileNameGenerator, fullName)
 }
 
 func loadWriter(
 	storage state.StateStorage,
-	nowProvider nowProvider,
+	clock Clock,
 	fileNameGenerator filename.FileNameGenerator,
 	fullName string,
 ) (*LogWriter, error) {
@@ -114,7 +230,12 @@ func loadWriter(
 	if err != nil {
 		return nil, err
 	}
-	return writer(s, storage, nowProvider, fileNameGenerator), nil
+	lw := writer(s, storage, clock, fileNameGenerator)
+	err = lw.reconcile()
+	if err != nil {
+		return nil, err
+	}
+	return lw, nil
 }
 
 func NewWriter(fullName string, config *state.Config) (io.WriteCloser, error) {
@@ -122,12 +243,12 @@ func NewWriter(fullName string, config *state.Config) (io.WriteCloser, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newWriter(storage, defaultNowProvider, fileNameGenerator, fullName, config)
+	return newWriter(storage, defaultClock, fileNameGenerator, fullName, config)
 }
 
 func newWriter(
 	storage state.StateStorage,
-	nowProvider nowProvider,
+	clock Clock,
 	fileNameGenerator filename.FileNameGenerator,
 	fullName string,
 	config *state.Config,
@@ -136,19 +257,20 @@ func newWriter(
 	if err != nil {
 		return nil, err
 	}
-	return writer(s, storage, nowProvider, fileNameGenerator), nil
+	return writer(s, storage, clock, fileNameGenerator), nil
 }
 
 func writer(
 	s *state.State,
 	storage state.StateStorage,
-	nowProvider nowProvider,
+	clock Clock,
 	fileNameGenerator filename.FileNameGenerator,
 ) *LogWriter {
 	return &LogWriter{
 		state:             s,
+		fs:                fsys.NewOSFileSystem(),
 		stateStorage:      storage,
-		nowProvider:       nowProvider,
+		clock:             clock,
 		fileNameGenerator: fileNameGenerator,
 	}
 }
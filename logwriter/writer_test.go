@@ -3,26 +3,49 @@ package logwriter
 import (
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"path"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/fsys"
 	"github.com/lorenzobenvenuti/loco/state"
 	"github.com/lorenzobenvenuti/loco/utils"
 	"github.com/stretchr/testify/assert"
 )
 
-type fakeNowProvider struct {
+type fakeClock struct {
 	now time.Time
 }
 
-func (p *fakeNowProvider) Now() time.Time {
+func (p *fakeClock) Now() time.Time {
 	return p.now
 }
 
-func newFakeNowProvider(nanos int64) *fakeNowProvider {
-	return &fakeNowProvider{now: time.Unix(0, nanos)}
+func newFakeClock(nanos int64) *fakeClock {
+	return &fakeClock{now: time.Unix(0, nanos)}
+}
+
+type tickingClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	tick time.Duration
+}
+
+func (c *tickingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(c.tick)
+	return c.now
+}
+
+func newTickingClock(tick time.Duration) *tickingClock {
+	return &tickingClock{now: time.Unix(0, 0), tick: tick}
 }
 
 type fakeFileNameGenerator struct {
@@ -45,7 +68,7 @@ func TestLoadWriter(t *testing.T) {
 	}
 	storage := state.NewMapStorage()
 	storage.Store(s)
-	lw, err := loadWriter(storage, newFakeNowProvider(42), newFakeFileNameGenerator(), "/path/to/file")
+	lw, err := loadWriter(storage, newFakeClock(42), newFakeFileNameGenerator(), "/path/to/file")
 	assert.Nil(t, err, "Loading the writer should not return an error")
 	expected := &state.State{
 		FullName:  "/path/to/file",
@@ -59,7 +82,7 @@ func TestLoadWriter(t *testing.T) {
 func TestNewWriter(t *testing.T) {
 	storage := state.NewMapStorage()
 	config := state.NewConfig(time.Hour*48, "%c")
-	lw, err := newWriter(storage, newFakeNowProvider(42), newFakeFileNameGenerator(), "/path/to/file", config)
+	lw, err := newWriter(storage, newFakeClock(42), newFakeFileNameGenerator(), "/path/to/file", config)
 	assert.Nil(t, err, "Creating the writer should not return an error")
 	s, err := storage.Load("/path/to/file")
 	assert.Nil(t, err, "Retrieving the writer from storage should not return an error")
@@ -86,7 +109,8 @@ func TestLogWriterFirstWrite(t *testing.T) {
 	storage := state.NewMapStorage()
 	lw := &LogWriter{
 		state:        s,
-		nowProvider:  newFakeNowProvider(42),
+		fs:           fsys.NewOSFileSystem(),
+		clock:        newFakeClock(42),
 		stateStorage: storage,
 	}
 	_, err := lw.Write([]byte("foo"))
@@ -122,7 +146,8 @@ func TestLogWriterFileExists(t *testing.T) {
 	storage.Store(s)
 	lw := &LogWriter{
 		state:        s,
-		nowProvider:  newFakeNowProvider(int64(time.Hour * 2)),
+		fs:           fsys.NewOSFileSystem(),
+		clock:        newFakeClock(int64(time.Hour * 2)),
 		stateStorage: storage,
 	}
 	_, err := lw.Write([]byte("foo"))
@@ -150,7 +175,7 @@ func TestLogWriterFileRotation(t *testing.T) {
 	rotatedPath := path.Join(dir, "file.log.bak")
 	err := ioutil.WriteFile(fullpath, []byte("bar"), 0755)
 	assert.NoError(t, err)
-	err = ioutil.WriteFile(rotatedPath, []byte("This should be overwritten"), 0755)
+	err = ioutil.WriteFile(rotatedPath, []byte("This should not be overwritten"), 0755)
 	assert.NoError(t, err)
 	storage := state.NewMapStorage()
 	s := &state.State{
@@ -162,7 +187,8 @@ func TestLogWriterFileRotation(t *testing.T) {
 	storage.Store(s)
 	lw := &LogWriter{
 		state:             s,
-		nowProvider:       newFakeNowProvider(int64(time.Hour * 27)),
+		fs:                fsys.NewOSFileSystem(),
+		clock:             newFakeClock(int64(time.Hour * 27)),
 		stateStorage:      storage,
 		fileNameGenerator: newFakeFileNameGenerator(),
 	}
@@ -173,6 +199,9 @@ func TestLogWriterFileRotation(t *testing.T) {
 	assert.Equal(t, []byte("foo"), bytes)
 	bytes, err = ioutil.ReadFile(rotatedPath)
 	assert.NoError(t, err)
+	assert.Equal(t, []byte("This should not be overwritten"), bytes)
+	bytes, err = ioutil.ReadFile(path.Join(dir, "file.log-1.bak"))
+	assert.NoError(t, err)
 	assert.Equal(t, []byte("bar"), bytes)
 	updated, err := storage.Load(fullpath)
 	assert.NoError(t, err)
@@ -185,3 +214,194 @@ func TestLogWriterFileRotation(t *testing.T) {
 	}
 	assert.Equal(t, expected, updated)
 }
+
+func readLines(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	lines := make([]string, 0)
+	for _, file := range files {
+		b, err := ioutil.ReadFile(path.Join(dir, file.Name()))
+		assert.NoError(t, err)
+		for _, line := range strings.Split(string(b), "\n") {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+func TestLogWriterConcurrentWritesWithRotation(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	fullpath := path.Join(dir, "file.log")
+	lw, err := New(
+		fullpath,
+		WithStateStorage(state.NewMapStorage()),
+		WithClock(newTickingClock(time.Minute)),
+		WithFileNameGenerator(filename.NewFileNameGenerator()),
+		WithConfig(state.NewConfig(time.Minute*5, "%c")),
+	)
+	assert.NoError(t, err)
+	const writers = 8
+	const writes = 200
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				_, err := lw.Write([]byte(fmt.Sprintf("writer %d line %d\n", i, j)))
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.NoError(t, lw.Close())
+	lines := readLines(t, dir)
+	assert.Equal(t, writers*writes, len(lines))
+	for _, line := range lines {
+		assert.Regexp(t, "^writer \\d+ line \\d+$", line)
+	}
+	assert.True(t, lw.state.Counter > 1, "File should have been rotated")
+}
+
+func TestLogWriterConcurrentLoggers(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	fullpath := path.Join(dir, "file.log")
+	lw, err := New(
+		fullpath,
+		WithStateStorage(state.NewMapStorage()),
+		WithClock(newTickingClock(time.Second)),
+		WithFileNameGenerator(filename.NewFileNameGenerator()),
+		WithConfig(state.NewConfig(time.Minute, "%c")),
+	)
+	assert.NoError(t, err)
+	loggers := []*log.Logger{log.New(lw, "first ", 0), log.New(lw, "second ", 0)}
+	var wg sync.WaitGroup
+	for _, l := range loggers {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(l *log.Logger) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					l.Printf("message %d", j)
+				}
+			}(l)
+		}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			assert.NoError(t, lw.Close())
+		}
+	}()
+	wg.Wait()
+	assert.NoError(t, lw.Close())
+	lines := readLines(t, dir)
+	assert.Equal(t, 800, len(lines))
+	counts := make(map[string]int)
+	for _, line := range lines {
+		counts[strings.SplitN(line, " ", 2)[0]]++
+	}
+	assert.Equal(t, map[string]int{"first": 400, "second": 400}, counts)
+}
+
+func newMemWriter(t *testing.T, fs fsys.FileSystem, clock Clock, opts ...Option) *LogWriter {
+	assert.NoError(t, fs.MkdirAll("/logs", 0755))
+	opts = append([]Option{
+		WithFileSystem(fs),
+		WithStateStorage(state.NewMapStorage()),
+		WithClock(clock),
+		WithFileNameGenerator(filename.NewFileNameGenerator()),
+		WithConfig(state.NewConfig(time.Hour, "%c")),
+	}, opts...)
+	lw, err := New("/logs/file.log", opts...)
+	assert.NoError(t, err)
+	return lw
+}
+
+func TestLogWriterRotationInMemory(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	lw := newMemWriter(t, fs, clock)
+	for i := 0; i < 3; i++ {
+		_, err := lw.Write([]byte(fmt.Sprintf("line %d", i)))
+		assert.NoError(t, err)
+		clock.now = clock.now.Add(time.Hour * 2)
+	}
+	assert.NoError(t, lw.Close())
+	for name, content := range map[string]string{
+		"/logs/file.log":   "line 2",
+		"/logs/file.1.log": "line 0",
+		"/logs/file.2.log": "line 1",
+	} {
+		b, err := fs.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+}
+
+func TestLogWriterRotate(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	lw := newMemWriter(t, fs, clock)
+	_, err := lw.Write([]byte("before"))
+	assert.NoError(t, err)
+	clock.now = clock.now.Add(time.Minute)
+	assert.NoError(t, lw.Rotate())
+	_, err = lw.Write([]byte("after"))
+	assert.NoError(t, err)
+	assert.NoError(t, lw.Close())
+	assert.Equal(t, 1, lw.state.Counter)
+	assert.Equal(t, clock.now, lw.state.RotatedAt)
+	b, _ := fs.ReadFile("/logs/file.1.log")
+	assert.Equal(t, "before", string(b))
+	b, _ = fs.ReadFile("/logs/file.log")
+	assert.Equal(t, "after", string(b))
+}
+
+func TestLogWriterRotateWithoutLogFile(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	lw := newMemWriter(t, fs, newFakeClock(0))
+	assert.NoError(t, lw.Rotate())
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.log"))
+	_, err := lw.Write([]byte("line"))
+	assert.NoError(t, err)
+	assert.NoError(t, lw.Close())
+	assert.Equal(t, 0, lw.state.Counter)
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.1.log"))
+	b, _ := fs.ReadFile("/logs/file.log")
+	assert.Equal(t, "line", string(b))
+}
+
+func TestLogWriterReturnsWriteErrors(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.InjectFault(fsys.OpWrite, "/logs/file.log", syscall.ENOSPC)
+	lw := newMemWriter(t, fs, newFakeClock(0))
+	defer lw.Close()
+	_, err := lw.Write([]byte("foo"))
+	assert.Equal(t, syscall.ENOSPC, err.(*os.PathError).Err)
+}
+
+func TestLogWriterReportsRotationErrors(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	clock := newFakeClock(0)
+	var errs []error
+	lw := newMemWriter(t, fs, clock, WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	defer lw.Close()
+	_, err := lw.Write([]byte("foo"))
+	assert.NoError(t, err)
+	fs.InjectFault(fsys.OpRename, "/logs/file.log", syscall.EACCES)
+	clock.now = clock.now.Add(time.Hour * 2)
+	_, err = lw.Write([]byte("bar"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, 0, lw.state.Counter)
+	assert.False(t, utils.ExistsIn(fs, "/logs/file.1.log"))
+	b, err := fs.ReadFile("/logs/file.log")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foobar"), b)
+}
@@ -0,0 +1,240 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/intervals"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Settings are the settings of a configuration given in a manifest; nil
+// fields are not set. Keys are the flags of loco config in camel case.
+type Settings struct {
+	Interval   *string           `yaml:"interval"`
+	Suffix     *string           `yaml:"suffix"`
+	Collision  *string           `yaml:"collision"`
+	UTC        *bool             `yaml:"utc"`
+	Naming     *string           `yaml:"naming"`
+	Retention  *int              `yaml:"retention"`
+	ArchiveDir *string           `yaml:"archiveDir"`
+	DirMode    *string           `yaml:"dirMode"`
+	FileMode   *string           `yaml:"fileMode"`
+	Owner      *string           `yaml:"owner"`
+	Group      *string           `yaml:"group"`
+	Labels     map[string]string `yaml:"labels"`
+}
+
+type Log struct {
+	File     string `yaml:"file"`
+	Settings `yaml:",inline"`
+}
+
+// Manifest lists the log files to configure; the defaults apply to every log
+// file and are overridden by its own settings. Labels are merged, so a log file
+// can add or change the labels of the defaults but can't remove them.
+type Manifest struct {
+	Defaults Settings `yaml:"defaults"`
+	Logs     []*Log   `yaml:"logs"`
+}
+
+func Read(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	d := yaml.NewDecoder(r)
+	d.KnownFields(true)
+	err := d.Decode(m)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return m, nil
+}
+
+func parseInterval(interval string) (time.Duration, error) {
+	if d, err := intervals.Parse(interval); err == nil {
+		return d, nil
+	}
+	return time.ParseDuration(interval)
+}
+
+func parseMode(mode string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("Invalid mode %s", mode)
+	}
+	return os.FileMode(m), nil
+}
+
+func (s *Settings) apply(c *state.Config) error {
+	if s.Interval != nil {
+		d, err := parseInterval(*s.Interval)
+		if err != nil {
+			return utils.Wrapf(err, "Cannot parse interval %s", *s.Interval)
+		}
+		c.Interval = d
+	}
+	if s.Suffix != nil {
+		c.Suffix = *s.Suffix
+	}
+	if s.Collision != nil {
+		c.Collision = *s.Collision
+	}
+	if s.UTC != nil {
+		c.UTC = *s.UTC
+	}
+	if s.Naming != nil {
+		c.Naming = *s.Naming
+	}
+	if s.Retention != nil {
+		c.Retention = *s.Retention
+	}
+	if s.ArchiveDir != nil {
+		c.ArchiveDir = *s.ArchiveDir
+	}
+	for _, m := range []struct {
+		value  *string
+		target *os.FileMode
+	}{{s.DirMode, &c.DirMode}, {s.FileMode, &c.FileMode}} {
+		if m.value != nil {
+			mode, err := parseMode(*m.value)
+			if err != nil {
+				return err
+			}
+			*m.target = mode
+		}
+	}
+	if s.Owner != nil {
+		c.Owner = *s.Owner
+	}
+	if s.Group != nil {
+		c.Group = *s.Group
+	}
+	for k, v := range s.Labels {
+		if c.Labels == nil {
+			c.Labels = make(map[string]string)
+		}
+		c.Labels[k] = v
+	}
+	return state.ValidatePolicies(c)
+}
+
+// Configs returns the configurations of the log files by name; relative names
+// are resolved against dir, the directory of the manifest. Settings left unset
+// are zero, as in a configuration created by loco config.
+func (m *Manifest) Configs(dir string) (map[string]*state.Config, error) {
+	configs := make(map[string]*state.Config)
+	for _, l := range m.Logs {
+		if l.File == "" {
+			return nil, fmt.Errorf("Missing file in manifest")
+		}
+		name := l.File
+		if !path.IsAbs(name) {
+			name = path.Join(dir, name)
+		}
+		if _, ok := configs[name]; ok {
+			return nil, fmt.Errorf("%s is listed more than once", name)
+		}
+		c := &state.Config{}
+		if err := m.Defaults.apply(c); err != nil {
+			return nil, utils.Wrapf(err, "Invalid defaults")
+		}
+		if err := l.Settings.apply(c); err != nil {
+			return nil, utils.Wrapf(err, "Invalid configuration of %s", name)
+		}
+		configs[name] = c
+	}
+	return configs, nil
+}
+
+// Actions that reconcile the state store with a manifest.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionRemove = "remove"
+)
+
+type Action struct {
+	Kind     string
+	FullName string
+	Config   *state.Config
+	Changes  []state.Change
+}
+
+// Plan compares the configurations with the stored states: missing states are
+// created, changed ones updated and, when pruning, states of log files that
+// aren't in the configurations are removed.
+func Plan(configs map[string]*state.Config, states []*state.State, prune bool) []*Action {
+	stored := make(map[string]*state.State)
+	for _, s := range states {
+		stored[s.FullName] = s
+	}
+	actions := make([]*Action, 0)
+	for name, c := range configs {
+		s, ok := stored[name]
+		if !ok {
+			actions = append(actions, &Action{ActionCreate, name, c, state.Diff(nil, c)})
+		} else if changes := state.Diff(&s.Config, c); len(changes) > 0 {
+			actions = append(actions, &Action{ActionUpdate, name, c, changes})
+		}
+	}
+	if prune {
+		for name, s := range stored {
+			if _, ok := configs[name]; !ok {
+				actions = append(actions, &Action{ActionRemove, name, nil, state.Diff(&s.Config, &state.Config{})})
+			}
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i].FullName < actions[j].FullName })
+	return actions
+}
+
+// Apply performs the actions; updates keep the counter and the rotation times.
+func Apply(storage state.StateStorage, actions []*Action) error {
+	for _, a := range actions {
+		var err error
+		switch a.Kind {
+		case ActionCreate:
+			_, err = state.NewState(storage, a.FullName, *a.Config)
+		case ActionUpdate:
+			var s *state.State
+			s, err = storage.Load(a.FullName)
+			if err == nil {
+				s.Config = *a.Config
+				err = storage.Store(s)
+			}
+		case ActionRemove:
+			err = storage.Remove(a.FullName)
+		}
+		if err != nil {
+			return utils.Wrapf(err, "Cannot %s %s", a.Kind, a.FullName)
+		}
+	}
+	return nil
+}
+
+var symbols = map[string]string{ActionCreate: "+", ActionUpdate: "~", ActionRemove: "-"}
+
+func WritePlan(w io.Writer, actions []*Action) error {
+	for _, a := range actions {
+		fmt.Fprintf(w, "%s %s\n", symbols[a.Kind], a.FullName)
+		for _, c := range a.Changes {
+			old, new := c.Old, c.New
+			if old == "" {
+				old = "-"
+			}
+			if new == "" {
+				new = "-"
+			}
+			if _, err := fmt.Fprintf(w, "    %s: %s -> %s\n", c.Setting, old, new); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package manifest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifest = `
+defaults:
+  interval: 1d
+  suffix: "%Y%m%d"
+  retention: 7
+  labels:
+    env: prod
+logs:
+  - file: /var/log/web.log
+    interval: 1h
+    suffix: "%Y%m%d%H"
+    labels:
+      team: web
+  - file: db.log
+    naming: shift
+    fileMode: "0640"
+`
+
+func TestConfigs(t *testing.T) {
+	m, err := Read(strings.NewReader(testManifest))
+	assert.NoError(t, err)
+	configs, err := m.Configs("/srv/logs")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*state.Config{
+		"/var/log/web.log": {
+			Interval:  time.Hour,
+			Suffix:    "%Y%m%d%H",
+			Retention: 7,
+			Labels:    map[string]string{"env": "prod", "team": "web"},
+		},
+		"/srv/logs/db.log": {
+			Interval:  time.Hour * 24,
+			Suffix:    "%Y%m%d",
+			Retention: 7,
+			Naming:    state.NamingShift,
+			FileMode:  0640,
+			Labels:    map[string]string{"env": "prod"},
+		},
+	}, configs)
+}
+
+func TestConfigsAcceptsDurations(t *testing.T) {
+	m, err := Read(strings.NewReader("logs:\n  - file: /a.log\n    interval: 24h0m0s\n"))
+	assert.NoError(t, err)
+	configs, err := m.Configs("/")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour*24, configs["/a.log"].Interval)
+}
+
+func TestInvalidManifests(t *testing.T) {
+	for _, content := range []string{
+		"logs:\n  - file: /a.log\n    unknown: 1\n",
+		"logs:\n  - file: /a.log\n    interval: often\n",
+		"logs:\n  - file: /a.log\n    dirMode: rwx\n",
+		"logs:\n  - file: /a.log\n    naming: shfit\n",
+		"defaults:\n  collision: apend\nlogs:\n  - file: /a.log\n",
+		"logs:\n  - interval: 1d\n",
+		"logs:\n  - file: /a.log\n  - file: /a.log\n",
+	} {
+		m, err := Read(strings.NewReader(content))
+		if err == nil {
+			_, err = m.Configs("/")
+		}
+		assert.Error(t, err, content)
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	storage := state.NewMapStorage()
+	rotatedAt := time.Date(2018, 12, 9, 0, 0, 0, 0, time.UTC)
+	storage.Store(&state.State{FullName: "/b.log", Counter: 3, RotatedAt: rotatedAt, Config: state.Config{Interval: time.Hour, Suffix: "%c"}})
+	storage.Store(&state.State{FullName: "/c.log", Config: state.Config{Interval: time.Hour, Suffix: "%c"}})
+	storage.Store(&state.State{FullName: "/d.log", Config: state.Config{Interval: time.Hour, Suffix: "%c"}})
+	configs := map[string]*state.Config{
+		"/a.log": {Interval: time.Hour, Suffix: "%c"},
+		"/b.log": {Interval: time.Hour * 2, Suffix: "%c"},
+		"/c.log": {Interval: time.Hour, Suffix: "%c"},
+	}
+	states, _ := storage.List()
+	assert.Len(t, Plan(configs, states, false), 2)
+	actions := Plan(configs, states, true)
+	assert.Equal(t, []string{ActionCreate, ActionUpdate, ActionRemove}, []string{actions[0].Kind, actions[1].Kind, actions[2].Kind})
+	assert.Equal(t, []state.Change{{Setting: "interval", Old: "1h0m0s", New: "2h0m0s"}}, actions[1].Changes)
+	assert.NoError(t, Apply(storage, actions))
+	states, _ = storage.List()
+	assert.Empty(t, Plan(configs, states, true))
+	b, err := storage.Load("/b.log")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, b.Counter)
+	assert.Equal(t, rotatedAt, b.RotatedAt)
+	_, err = storage.Load("/d.log")
+	assert.Error(t, err)
+}
+
+func TestWritePlan(t *testing.T) {
+	var b bytes.Buffer
+	err := WritePlan(&b, []*Action{
+		{Kind: ActionCreate, FullName: "/a.log", Changes: []state.Change{{Setting: "interval", New: "1h0m0s"}}},
+		{Kind: ActionRemove, FullName: "/d.log", Changes: []state.Change{{Setting: "suffix", Old: "%c"}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "+ /a.log\n    interval: - -> 1h0m0s\n- /d.log\n    suffix: %c -> -\n", b.String())
+}
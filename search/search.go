@@ -0,0 +1,141 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"sync"
+
+	"github.com/lorenzobenvenuti/loco/archives"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+const maxLineLength = 1024 * 1024
+
+// Options of a search: the number of lines printed before and after each
+// match, and whether lines are prefixed by their number.
+type Options struct {
+	Before      int
+	After       int
+	LineNumbers bool
+}
+
+type line struct {
+	number int
+	text   string
+}
+
+type fileSearch struct {
+	name    string
+	re      *regexp.Regexp
+	opts    Options
+	out     bytes.Buffer
+	matches int
+	printed int
+}
+
+// print writes a line as grep does: matches are separated from the prefix
+// by a colon, context lines by a dash.
+func (s *fileSearch) print(l line, match bool) {
+	sep := "-"
+	if match {
+		sep = ":"
+	}
+	if s.opts.LineNumbers {
+		fmt.Fprintf(&s.out, "%s%s%d%s%s\n", s.name, sep, l.number, sep, l.text)
+	} else {
+		fmt.Fprintf(&s.out, "%s%s%s\n", s.name, sep, l.text)
+	}
+	s.printed = l.number
+}
+
+func (s *fileSearch) run(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	before := make([]line, 0, s.opts.Before)
+	after := 0
+	for n := 1; scanner.Scan(); n++ {
+		l := line{n, scanner.Text()}
+		switch {
+		case s.re.MatchString(l.text):
+			s.matches++
+			first := n - len(before)
+			if s.printed > 0 && first > s.printed+1 && (s.opts.Before > 0 || s.opts.After > 0) {
+				s.out.WriteString("--\n")
+			}
+			for _, b := range before {
+				s.print(b, false)
+			}
+			before = before[:0]
+			s.print(l, true)
+			after = s.opts.After
+		case after > 0:
+			s.print(l, false)
+			after--
+		case s.opts.Before > 0:
+			if len(before) == s.opts.Before {
+				before = append(before[:0], before[1:]...)
+			}
+			before = append(before, l)
+		}
+	}
+	return scanner.Err()
+}
+
+func searchFile(fs fsys.FileSystem, f *archives.File, re *regexp.Regexp, opts Options) (*fileSearch, error) {
+	r, err := archives.Open(fs, f)
+	if err != nil {
+		return nil, utils.Wrapf(err, "Cannot open %s", f.Path)
+	}
+	defer r.Close()
+	s := &fileSearch{name: f.Path, re: re, opts: opts}
+	err = s.run(r)
+	if err != nil {
+		return nil, utils.Wrapf(err, "Cannot read %s", f.Path)
+	}
+	return s, nil
+}
+
+// Grep searches the files in parallel, decompressing them, and writes the
+// matching lines to w prefixed by the name of their file, in the order of the
+// files. It returns the number of matching lines.
+func Grep(w io.Writer, fs fsys.FileSystem, files []*archives.File, re *regexp.Regexp, opts Options) (int, error) {
+	results := make([]*fileSearch, len(files))
+	errs := make([]error, len(files))
+	sem := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i, f := range files {
+		wg.Add(1)
+		go func(i int, f *archives.File) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = searchFile(fs, f, re, opts)
+		}(i, f)
+	}
+	wg.Wait()
+	matches := 0
+	written := false
+	for i, s := range results {
+		if errs[i] != nil {
+			return matches, errs[i]
+		}
+		if s.out.Len() == 0 {
+			continue
+		}
+		if written && (opts.Before > 0 || opts.After > 0) {
+			io.WriteString(w, "--\n")
+		}
+		_, err := w.Write(s.out.Bytes())
+		if err != nil {
+			return matches, err
+		}
+		written = true
+		matches += s.matches
+	}
+	return matches, nil
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/lorenzobenvenuti/loco/archives"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipped(content string) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write([]byte(content))
+	w.Close()
+	return b.Bytes()
+}
+
+func TestGrepPrefixesMatchesWithTheFileName(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.WriteFile("/app.1.log.gz", gzipped("a\nerror 1\nb\nc\nd\nerror 2\n"), 0644)
+	fs.WriteFile("/app.log", []byte("e\nf\nerror 3\n"), 0644)
+	files := []*archives.File{{Path: "/app.1.log.gz", Compression: "gzip"}, {Path: "/app.log"}}
+	var b bytes.Buffer
+	n, err := Grep(&b, fs, files, regexp.MustCompile("error"), Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "/app.1.log.gz:error 1\n/app.1.log.gz:error 2\n/app.log:error 3\n", b.String())
+}
+
+func TestGrepWithContext(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.WriteFile("/app.1.log.gz", gzipped("a\nerror 1\nb\nc\nd\nerror 2\n"), 0644)
+	fs.WriteFile("/app.log", []byte("e\nf\nerror 3\n"), 0644)
+	files := []*archives.File{{Path: "/app.1.log.gz", Compression: "gzip"}, {Path: "/app.log"}}
+	var b bytes.Buffer
+	_, err := Grep(&b, fs, files, regexp.MustCompile("error"), Options{Before: 1, After: 1, LineNumbers: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "/app.1.log.gz-1-a\n"+
+		"/app.1.log.gz:2:error 1\n"+
+		"/app.1.log.gz-3-b\n"+
+		"--\n"+
+		"/app.1.log.gz-5-d\n"+
+		"/app.1.log.gz:6:error 2\n"+
+		"--\n"+
+		"/app.log-2-f\n"+
+		"/app.log:3:error 3\n", b.String())
+}
+
+func TestGrepWithOverlappingContext(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.WriteFile("/app.log", []byte("x\ny\nx\nz\n"), 0644)
+	var b bytes.Buffer
+	n, err := Grep(&b, fs, []*archives.File{{Path: "/app.log"}}, regexp.MustCompile("x"), Options{Before: 2, After: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "/app.log:x\n/app.log-y\n/app.log:x\n/app.log-z\n", b.String())
+}
+
+func TestGrepReportsUnreadableFiles(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.WriteFile("/app.log", []byte("error\n"), 0644)
+	fs.InjectFault(fsys.OpOpen, "/app.log", errors.New("boom"))
+	_, err := Grep(&bytes.Buffer{}, fs, []*archives.File{{Path: "/app.log"}}, regexp.MustCompile("error"), Options{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/archives"
+	"github.com/lorenzobenvenuti/loco/filename"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/logwriter"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// Step is a simulated rotation: the archive written, the name the suffix
+// produced if it was taken (Collision) and the archives removed by retention.
+// Err is set if the rotation fails.
+type Step struct {
+	Time      time.Time
+	Counter   int
+	Archive   string
+	Collision string
+	Deleted   []string
+	Err       error
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func walk(fs fsys.FileSystem, dir string, visit func(string)) {
+	infos, _ := fs.ReadDir(dir)
+	for _, info := range infos {
+		name := path.Join(dir, info.Name())
+		if info.IsDir() {
+			walk(fs, name, visit)
+		} else {
+			visit(name)
+		}
+	}
+}
+
+// contents returns the content of every file, by name. Each file holds the
+// labels of the rotations whose data it contains, so that removed data can be
+// told apart from shifted files.
+func contents(fs fsys.FileSystem) map[string]string {
+	m := make(map[string]string)
+	walk(fs, "/", func(name string) {
+		b, _ := fs.ReadFile(name)
+		m[name] = string(b)
+	})
+	return m
+}
+
+func deleted(before map[string]string, after map[string]string) []string {
+	var all strings.Builder
+	for _, c := range after {
+		all.WriteString(c)
+	}
+	names := make([]string, 0)
+	for name, c := range before {
+		if c != "" && !strings.Contains(all.String(), c) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// seed copies the log file and the archives of s to an in-memory file system;
+// files are labeled with their names.
+func seed(fs fsys.FileSystem, s *state.State) (*fsys.MemFileSystem, error) {
+	mem := fsys.NewMemFileSystem()
+	list, err := archives.List(fs, s)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range list {
+		mem.MkdirAll(path.Dir(a.Path), 0755)
+		mem.WriteFile(a.Path, []byte("<"+a.Path+">"), 0644)
+	}
+	mem.MkdirAll(path.Dir(s.FullName), 0755)
+	return mem, nil
+}
+
+// Simulate runs count rotations of the log file of s, one every interval
+// starting from from, on a copy of its archives in memory: the file system is
+// only read.
+func Simulate(fs fsys.FileSystem, s *state.State, from time.Time, count int) ([]*Step, error) {
+	mem, err := seed(fs, s)
+	if err != nil {
+		return nil, err
+	}
+	c := &clock{now: from}
+	mem.SetClock(c.Now)
+	sim := &state.State{FullName: s.FullName, Config: s.Config, Counter: s.Counter, CreatedAt: from, RotatedAt: from}
+	storage := state.NewMapStorage()
+	err = storage.Store(sim)
+	if err != nil {
+		return nil, err
+	}
+	generator := filename.NewFileNameGenerator()
+	var archived string
+	lw, err := logwriter.New(
+		s.FullName,
+		logwriter.WithFileSystem(mem),
+		logwriter.WithStateStorage(storage),
+		logwriter.WithClock(c),
+		logwriter.WithFileNameGenerator(generator),
+		logwriter.WithHooks(logwriter.Hooks{OnRotate: func(e logwriter.RotateEvent) { archived = e.NewPath }}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer lw.Close()
+	steps := make([]*Step, 0, count)
+	for i := 1; i <= count; i++ {
+		_, err := lw.Write([]byte(fmt.Sprintf("<rotation %d>", i)))
+		if err != nil {
+			return nil, err
+		}
+		c.now = from.Add(s.Config.Interval * time.Duration(i))
+		step := &Step{Time: c.now, Counter: s.Counter + i}
+		next := &state.State{FullName: s.FullName, Config: s.Config, Counter: step.Counter, RotatedAt: c.now}
+		if name := generator.FileName(next); s.Config.Naming != state.NamingShift && utils.ExistsIn(mem, name) {
+			step.Collision = name
+		}
+		before := contents(mem)
+		archived = ""
+		step.Err = lw.Rotate()
+		if step.Err != nil && !errors.Is(step.Err, logwriter.ErrArchiveExists) {
+			return nil, step.Err
+		}
+		step.Archive = archived
+		step.Deleted = deleted(before, contents(mem))
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func WriteSteps(w io.Writer, steps []*Step) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', tabwriter.TabIndent)
+	fmt.Fprintf(tw, "TIME\tCOUNTER\tARCHIVE\tNOTES\n")
+	for _, s := range steps {
+		archive := s.Archive
+		notes := make([]string, 0)
+		if s.Err != nil {
+			archive = "-"
+			notes = append(notes, fmt.Sprintf("fails: %s", s.Err))
+		} else if s.Collision != "" && s.Collision != s.Archive {
+			notes = append(notes, fmt.Sprintf("collides with %s", s.Collision))
+		} else if s.Collision != "" {
+			notes = append(notes, "collides, appended to the existing archive")
+		}
+		if len(s.Deleted) > 0 {
+			notes = append(notes, fmt.Sprintf("deletes %s", strings.Join(s.Deleted, ", ")))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", s.Time.Format(time.RFC822), s.Counter, archive, strings.Join(notes, "; "))
+	}
+	return tw.Flush()
+}
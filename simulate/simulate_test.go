@@ -0,0 +1,87 @@
+package simulate
+
+import (
+	"bytes"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func memFiles(names ...string) *fsys.MemFileSystem {
+	fs := fsys.NewMemFileSystem()
+	for _, name := range names {
+		fs.MkdirAll(path.Dir(name), 0755)
+		fs.WriteFile(name, []byte(name), 0644)
+	}
+	return fs
+}
+
+func archiveNames(steps []*Step) []string {
+	names := make([]string, 0)
+	for _, s := range steps {
+		names = append(names, s.Archive)
+	}
+	return names
+}
+
+var from = time.Date(2018, 12, 9, 0, 0, 0, 0, time.UTC)
+
+func TestSimulate(t *testing.T) {
+	fs := memFiles("/logs/app.log")
+	s := &state.State{FullName: "/logs/app.log", Counter: 4, Config: state.Config{Interval: time.Hour * 24, Suffix: "%Y%m%d", UTC: true}}
+	steps, err := Simulate(fs, s, from, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/logs/app.20181210.log", "/logs/app.20181211.log", "/logs/app.20181212.log"}, archiveNames(steps))
+	assert.Equal(t, from.Add(time.Hour*48), steps[1].Time)
+	assert.Equal(t, 6, steps[1].Counter)
+	assert.False(t, utils.ExistsIn(fs, "/logs/app.20181210.log"))
+}
+
+func TestSimulateFlagsCollisions(t *testing.T) {
+	fs := memFiles("/logs/app.20181209.log")
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Interval: time.Hour * 12, Suffix: "%Y%m%d", UTC: true}}
+	steps, err := Simulate(fs, s, from, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/logs/app.20181209-1.log", "/logs/app.20181210.log"}, archiveNames(steps))
+	assert.Equal(t, "/logs/app.20181209.log", steps[0].Collision)
+	assert.Equal(t, "", steps[1].Collision)
+	s.Config.Collision = state.CollisionFail
+	steps, err = Simulate(fs, s, from, 1)
+	assert.NoError(t, err)
+	assert.Error(t, steps[0].Err)
+}
+
+func TestSimulateRetention(t *testing.T) {
+	fs := memFiles("/logs/app.1.log", "/logs/app.2.log")
+	s := &state.State{FullName: "/logs/app.log", Counter: 2, Config: state.Config{Interval: time.Hour, Suffix: "%c", Retention: 2}}
+	steps, err := Simulate(fs, s, from, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/logs/app.1.log"}, steps[0].Deleted)
+	assert.Equal(t, []string{"/logs/app.2.log"}, steps[1].Deleted)
+	assert.True(t, utils.ExistsIn(fs, "/logs/app.1.log"))
+}
+
+func TestSimulateShiftedRetention(t *testing.T) {
+	fs := memFiles("/logs/app.log.1", "/logs/app.log.2")
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Interval: time.Hour, Naming: state.NamingShift, Retention: 2}}
+	steps, err := Simulate(fs, s, from, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/logs/app.log.1", "/logs/app.log.1"}, archiveNames(steps))
+	assert.Equal(t, []string{"/logs/app.log.2"}, steps[0].Deleted)
+	assert.Equal(t, []string{"/logs/app.log.2"}, steps[1].Deleted)
+}
+
+func TestWriteSteps(t *testing.T) {
+	var b bytes.Buffer
+	err := WriteSteps(&b, []*Step{
+		{Time: from, Counter: 1, Archive: "/logs/app.20181209-1.log", Collision: "/logs/app.20181209.log", Deleted: []string{"/logs/app.20181201.log"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "TIME                COUNTER ARCHIVE                  NOTES\n"+
+		"09 Dec 18 00:00 UTC 1       /logs/app.20181209-1.log collides with /logs/app.20181209.log; deletes /logs/app.20181201.log\n", b.String())
+}
@@ -1,10 +1,49 @@
 package state
 
-import "time"
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Policies applied when the name of a rotated file is already taken: the
+// default is to add a uniquifier to the new name.
+const (
+	CollisionUnique = "unique"
+	CollisionAppend = "append"
+	CollisionFail   = "fail"
+)
+
+// Naming strategies of rotated files: the suffix strategy adds the configured
+// suffix, the shift strategy renames file.1 to file.2 and so on, so that
+// file.1 is always the newest archive.
+const (
+	NamingSuffix = "suffix"
+	NamingShift  = "shift"
+)
+
+// DefaultDirMode is the mode of the directories created when DirMode isn't set.
+const DefaultDirMode os.FileMode = 0755
 
 type Config struct {
-	Interval time.Duration
-	Suffix   string
+	Interval  time.Duration
+	Suffix    string
+	Collision string
+	UTC       bool
+	Naming    string
+	Retention int
+	// ArchiveDir is where rotated files are moved, rendered with the tokens of
+	// the suffix; relative paths are resolved against the log file directory.
+	ArchiveDir string
+	DirMode    os.FileMode
+	FileMode   os.FileMode
+	// Owner and Group of log files and archives, as names or ids.
+	Owner string
+	Group string
+	// Labels are free key/value pairs used to select log files.
+	Labels map[string]string
+	// Sources tells where the interval and the suffix come from.
+	Sources map[string]string
 }
 
 func NewConfig(interval time.Duration, suffix string) *Config {
@@ -13,3 +52,19 @@ func NewConfig(interval time.Duration, suffix string) *Config {
 		Suffix:   suffix,
 	}
 }
+
+// ValidatePolicies returns an error if the collision policy or the naming
+// strategy is unknown; empty values select the defaults.
+func ValidatePolicies(c *Config) error {
+	switch c.Collision {
+	case "", CollisionUnique, CollisionAppend, CollisionFail:
+	default:
+		return fmt.Errorf("Unknown collision policy %s", c.Collision)
+	}
+	switch c.Naming {
+	case "", NamingSuffix, NamingShift:
+	default:
+		return fmt.Errorf("Unknown naming %s", c.Naming)
+	}
+	return nil
+}
@@ -22,3 +22,10 @@ func TestNewConfig(t *testing.T) {
 	}
 	assert.Equal(t, expected, s)
 }
+
+func TestValidatePolicies(t *testing.T) {
+	assert.NoError(t, ValidatePolicies(&Config{}))
+	assert.NoError(t, ValidatePolicies(&Config{Collision: CollisionAppend, Naming: NamingShift}))
+	assert.Error(t, ValidatePolicies(&Config{Collision: "apend"}))
+	assert.Error(t, ValidatePolicies(&Config{Naming: "shfit"}))
+}
@@ -0,0 +1,80 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Change is a setting of a configuration whose value differs between two
+// configurations; an empty value means the setting is unset.
+type Change struct {
+	Setting string
+	Old     string
+	New     string
+}
+
+func formatMode(m os.FileMode) string {
+	if m == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%04o", uint32(m))
+}
+
+func settings(c *Config) [][2]string {
+	interval := ""
+	if c.Interval != 0 {
+		interval = c.Interval.String()
+	}
+	retention := ""
+	if c.Retention != 0 {
+		retention = strconv.Itoa(c.Retention)
+	}
+	return [][2]string{
+		{"interval", interval},
+		{"suffix", c.Suffix},
+		{"naming", c.Naming},
+		{"retention", retention},
+		{"collision", c.Collision},
+		{"utc", strconv.FormatBool(c.UTC)},
+		{"archive-dir", c.ArchiveDir},
+		{"dir-mode", formatMode(c.DirMode)},
+		{"file-mode", formatMode(c.FileMode)},
+		{"owner", c.Owner},
+		{"group", c.Group},
+		{"labels", formatLabels(c.Labels)},
+	}
+}
+
+// Diff returns the settings changed from old to new; a nil old configuration
+// is a configuration being created.
+func Diff(old *Config, new *Config) []Change {
+	if old == nil {
+		old = &Config{}
+	}
+	changes := make([]Change, 0)
+	n := settings(new)
+	for i, o := range settings(old) {
+		if o[1] != n[i][1] {
+			changes = append(changes, Change{o[0], o[1], n[i][1]})
+		}
+	}
+	return changes
+}
+
+func WriteChanges(w io.Writer, changes []Change) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', tabwriter.TabIndent)
+	for _, c := range changes {
+		old, new := c.Old, c.New
+		if old == "" {
+			old = "-"
+		}
+		if new == "" {
+			new = "-"
+		}
+		fmt.Fprintf(tw, "%s:\t%s\t->\t%s\n", c.Setting, old, new)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,33 @@
+package state
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Config{Interval: time.Hour, Suffix: "%c", Retention: 3, Labels: map[string]string{"team": "web"}}
+	new := &Config{Interval: time.Hour * 24, Suffix: "%c", UTC: true, FileMode: 0640, Labels: map[string]string{"team": "web", "env": "prod"}}
+	assert.Equal(t, []Change{
+		{"interval", "1h0m0s", "24h0m0s"},
+		{"retention", "3", ""},
+		{"utc", "false", "true"},
+		{"file-mode", "", "0640"},
+		{"labels", "team=web", "env=prod,team=web"},
+	}, Diff(old, new))
+	assert.Empty(t, Diff(old, old))
+}
+
+func TestDiffOfNewConfig(t *testing.T) {
+	assert.Equal(t, []Change{{"interval", "", "1h0m0s"}, {"suffix", "", "%c"}}, Diff(nil, NewConfig(time.Hour, "%c")))
+}
+
+func TestWriteChanges(t *testing.T) {
+	var b bytes.Buffer
+	err := WriteChanges(&b, []Change{{"interval", "1h0m0s", "24h0m0s"}, {"retention", "3", ""}})
+	assert.NoError(t, err)
+	assert.Equal(t, "interval:  1h0m0s -> 24h0m0s\nretention: 3      -> -\n", b.String())
+}
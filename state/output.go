@@ -0,0 +1,209 @@
+package state
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of a list of states.
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputCSV   = "csv"
+	OutputYAML  = "yaml"
+)
+
+// Filter selects states by the path of their log file and by their labels;
+// when Stale is set only states whose log file doesn't exist are selected.
+type Filter struct {
+	PathPrefix string
+	Stale      bool
+	Labels     map[string]string
+}
+
+func (f *Filter) Apply(states []*State, exists func(string) bool) []*State {
+	selected := make([]*State, 0)
+	for _, s := range states {
+		if !strings.HasPrefix(s.FullName, f.PathPrefix) {
+			continue
+		}
+		if f.Stale && exists(s.FullName) {
+			continue
+		}
+		matches := true
+		for k, v := range f.Labels {
+			if value, ok := s.Config.Labels[k]; !ok || value != v {
+				matches = false
+			}
+		}
+		if matches {
+			selected = append(selected, s)
+		}
+	}
+	return selected
+}
+
+var sortKeys = map[string]func(a *State, b *State) bool{
+	"name":     func(a *State, b *State) bool { return a.FullName < b.FullName },
+	"created":  func(a *State, b *State) bool { return a.CreatedAt.Before(b.CreatedAt) },
+	"rotated":  func(a *State, b *State) bool { return a.RotatedAt.Before(b.RotatedAt) },
+	"interval": func(a *State, b *State) bool { return a.Config.Interval < b.Config.Interval },
+	"counter":  func(a *State, b *State) bool { return a.Counter < b.Counter },
+}
+
+// SortKeys returns the keys accepted by SortStates.
+func SortKeys() []string {
+	keys := make([]string, 0, len(sortKeys))
+	for k := range sortKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func SortStates(states []*State, key string, reverse bool) error {
+	less, ok := sortKeys[key]
+	if !ok {
+		return fmt.Errorf("Unknown sort key %s", key)
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		if reverse {
+			return less(states[j], states[i])
+		}
+		return less(states[i], states[j])
+	})
+	return nil
+}
+
+type stateView struct {
+	File       string            `json:"file" yaml:"file"`
+	CreatedAt  *time.Time        `json:"createdAt,omitempty" yaml:"createdAt,omitempty"`
+	RotatedAt  *time.Time        `json:"rotatedAt,omitempty" yaml:"rotatedAt,omitempty"`
+	Counter    int               `json:"counter" yaml:"counter"`
+	Interval   string            `json:"interval" yaml:"interval"`
+	Suffix     string            `json:"suffix" yaml:"suffix"`
+	Naming     string            `json:"naming,omitempty" yaml:"naming,omitempty"`
+	Retention  int               `json:"retention,omitempty" yaml:"retention,omitempty"`
+	ArchiveDir string            `json:"archiveDir,omitempty" yaml:"archiveDir,omitempty"`
+	Collision  string            `json:"collision,omitempty" yaml:"collision,omitempty"`
+	UTC        bool              `json:"utc,omitempty" yaml:"utc,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func newStateView(s *State) *stateView {
+	return &stateView{
+		File:       s.FullName,
+		CreatedAt:  optionalTime(s.CreatedAt),
+		RotatedAt:  optionalTime(s.RotatedAt),
+		Counter:    s.Counter,
+		Interval:   s.Config.Interval.String(),
+		Suffix:     s.Config.Suffix,
+		Naming:     s.Config.Naming,
+		Retention:  s.Config.Retention,
+		ArchiveDir: s.Config.ArchiveDir,
+		Collision:  s.Config.Collision,
+		UTC:        s.Config.UTC,
+		Labels:     s.Config.Labels,
+	}
+}
+
+func stateViews(states []*State) []*stateView {
+	views := make([]*stateView, 0, len(states))
+	for _, s := range states {
+		views = append(views, newStateView(s))
+	}
+	return views
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func writeCSV(w io.Writer, states []*State) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"file", "created_at", "rotated_at", "counter", "interval", "suffix", "labels"})
+	for _, s := range states {
+		cw.Write([]string{
+			s.FullName,
+			formatTime(s.CreatedAt),
+			formatTime(s.RotatedAt),
+			strconv.Itoa(s.Counter),
+			s.Config.Interval.String(),
+			s.Config.Suffix,
+			formatLabels(s.Config.Labels),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteStatesAs writes the states in one of the output formats.
+func WriteStatesAs(w io.Writer, states []*State, output string) error {
+	switch output {
+	case OutputTable, "":
+		return WriteStates(w, states)
+	case OutputJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		e.SetEscapeHTML(false)
+		return e.Encode(stateViews(states))
+	case OutputCSV:
+		return writeCSV(w, states)
+	case OutputYAML:
+		e := yaml.NewEncoder(w)
+		err := e.Encode(stateViews(states))
+		if err != nil {
+			return err
+		}
+		return e.Close()
+	}
+	return fmt.Errorf("Unknown output %s", output)
+}
+
+// WriteStatesWithTemplate executes a text/template for each state, writing a
+// line for each of them.
+func WriteStatesWithTemplate(w io.Writer, states []*State, format string) error {
+	t, err := template.New("format").Parse(format)
+	if err != nil {
+		return err
+	}
+	for _, s := range states {
+		err := t.Execute(w, s)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
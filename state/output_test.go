@@ -0,0 +1,100 @@
+package state
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func outputStates() []*State {
+	return []*State{
+		{
+			FullName:  "/var/log/b.log",
+			CreatedAt: time.Date(2018, 11, 18, 17, 15, 12, 0, time.UTC),
+			RotatedAt: time.Date(2018, 11, 19, 17, 15, 12, 0, time.UTC),
+			Counter:   3,
+			Config:    Config{Interval: time.Hour * 24, Suffix: "%c", Labels: map[string]string{"team": "web", "env": "prod"}},
+		},
+		{
+			FullName: "/srv/a&b.log",
+			Config:   Config{Interval: time.Hour, Suffix: "%Y%m%d%H"},
+		},
+	}
+}
+
+func TestFilter(t *testing.T) {
+	exists := func(name string) bool { return name == "/var/log/b.log" }
+	for _, c := range []struct {
+		filter   Filter
+		expected int
+	}{
+		{Filter{}, 2},
+		{Filter{PathPrefix: "/var/"}, 1},
+		{Filter{Stale: true}, 1},
+		{Filter{Labels: map[string]string{"team": "web"}}, 1},
+		{Filter{Labels: map[string]string{"team": "db"}}, 0},
+	} {
+		assert.Equal(t, c.expected, len(c.filter.Apply(outputStates(), exists)), c.filter)
+	}
+	assert.Equal(t, "/srv/a&b.log", (&Filter{Stale: true}).Apply(outputStates(), exists)[0].FullName)
+}
+
+func TestSortStates(t *testing.T) {
+	states := outputStates()
+	assert.NoError(t, SortStates(states, "name", false))
+	assert.Equal(t, "/srv/a&b.log", states[0].FullName)
+	assert.NoError(t, SortStates(states, "interval", true))
+	assert.Equal(t, "/var/log/b.log", states[0].FullName)
+	assert.Error(t, SortStates(states, "size", false))
+	assert.Equal(t, []string{"counter", "created", "interval", "name", "rotated"}, SortKeys())
+}
+
+func TestWriteStatesAsJSON(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, WriteStatesAs(&b, outputStates()[1:], OutputJSON))
+	assert.Equal(t, `[
+  {
+    "file": "/srv/a&b.log",
+    "counter": 0,
+    "interval": "1h0m0s",
+    "suffix": "%Y%m%d%H"
+  }
+]
+`, b.String())
+}
+
+func TestWriteStatesAsCSV(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, WriteStatesAs(&b, outputStates(), OutputCSV))
+	assert.Equal(t, "file,created_at,rotated_at,counter,interval,suffix,labels\n"+
+		"/var/log/b.log,2018-11-18T17:15:12Z,2018-11-19T17:15:12Z,3,24h0m0s,%c,\"env=prod,team=web\"\n"+
+		"/srv/a&b.log,,,0,1h0m0s,%Y%m%d%H,\n", b.String())
+}
+
+func TestWriteStatesAsYAML(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, WriteStatesAs(&b, outputStates()[:1], OutputYAML))
+	assert.Equal(t, `- file: /var/log/b.log
+  createdAt: 2018-11-18T17:15:12Z
+  rotatedAt: 2018-11-19T17:15:12Z
+  counter: 3
+  interval: 24h0m0s
+  suffix: '%c'
+  labels:
+    env: prod
+    team: web
+`, b.String())
+}
+
+func TestWriteStatesAsUnknownOutput(t *testing.T) {
+	assert.Error(t, WriteStatesAs(&bytes.Buffer{}, outputStates(), "xml"))
+}
+
+func TestWriteStatesWithTemplate(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, WriteStatesWithTemplate(&b, outputStates(), "{{.FullName}} {{.Config.Interval}}"))
+	assert.Equal(t, "/var/log/b.log 24h0m0s\n/srv/a&b.log 1h0m0s\n", b.String())
+	assert.Error(t, WriteStatesWithTemplate(&b, outputStates(), "{{.Missing"))
+}
@@ -1,18 +1,36 @@
 package state
 
 import (
-	"html/template"
 	"io"
 	"text/tabwriter"
+	"text/template"
 	"time"
 )
 
+// Rotation is the intent of renaming From to To, recorded before the rename so
+// that an interrupted rotation can be completed or rolled back. When Merge is
+// set From is appended to the existing To; when Shift is set the existing
+// archives are shifted before renaming. Size and ModTime identify the file
+// being rotated, so that it can be told apart from a log file created after the
+// rotation.
+type Rotation struct {
+	From      string
+	To        string
+	Merge     bool
+	Shift     bool
+	Counter   int
+	RotatedAt time.Time
+	Size      int64
+	ModTime   time.Time
+}
+
 type State struct {
 	FullName  string
 	CreatedAt time.Time
 	RotatedAt time.Time
 	Counter   int
 	Config    Config
+	Pending   *Rotation
 }
 
 func (s *State) formatDate(t time.Time) string {
@@ -78,3 +78,10 @@ func TestWriteStates(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "FILE           CREATED AT          ROTATED AT          INTERVAL SUFFIX\n/path/to/file1 -                   -                   24h0m0s  %c\n/path/to/file2 18 Nov 18 17:15 UTC 18 Nov 18 18:15 UTC 336h0m0s %Y%m%d\n\n", buf.String())
 }
+
+func TestWriteStatesDoesNotEscapePaths(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteStates(&buf, []*State{{FullName: "/logs/tom&jerry's.log"}})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "/logs/tom&jerry's.log")
+}
@@ -2,10 +2,15 @@ package state
 
 import (
 	"fmt"
-	"io/ioutil"
+	"math/rand"
 	"os"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/lorenzobenvenuti/loco/fsys"
 	"github.com/lorenzobenvenuti/loco/utils"
 )
 
@@ -17,52 +22,171 @@ type StateStorage interface {
 }
 
 type fileStateStorage struct {
+	fs        fsys.FileSystem
 	dir       string
 	marshaler stateMarshaller
 }
 
 func (s *fileStateStorage) filename(fullName string) string {
-	return fmt.Sprintf("%s.json", utils.MD5(fullName))
+	return path.Join(s.dir, fmt.Sprintf("%s.json", utils.MD5(fullName)))
+}
+
+func backupFilename(filename string) string {
+	return filename + ".bak"
+}
+
+// tempPrefix is the prefix of the temporary files used to replace filename.
+func tempPrefix(filename string) string {
+	return filename + ".tmp"
+}
+
+// writeTemp writes and syncs b to a new temporary file next to filename,
+// returning its name. Every call uses a different file, so that concurrent
+// writers never rename each other's content.
+func (s *fileStateStorage) writeTemp(filename string, b []byte) (string, error) {
+	for i := 0; i < 10000; i++ {
+		tmp := tempPrefix(filename) + strconv.FormatUint(uint64(rand.Uint32()), 10)
+		f, err := s.fs.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = f.Write(b)
+		if err == nil {
+			err = f.Sync()
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			s.fs.Remove(tmp)
+			return "", err
+		}
+		return tmp, nil
+	}
+	return "", fmt.Errorf("Cannot create a temporary file for %s", filename)
+}
+
+// storeFile replaces filename atomically: the content is written and synced to
+// a temporary file that is then renamed. The previous version is kept as a
+// backup.
+func (s *fileStateStorage) storeFile(filename string, b []byte) error {
+	tmp, err := s.writeTemp(filename, b)
+	if err != nil {
+		return utils.Wrapf(err, "Cannot write %s", filename)
+	}
+	err = s.fs.Rename(filename, backupFilename(filename))
+	if err != nil && !os.IsNotExist(err) {
+		s.fs.Remove(tmp)
+		return utils.Wrapf(err, "Cannot backup %s", filename)
+	}
+	err = s.fs.Rename(tmp, filename)
+	if err != nil {
+		s.fs.Remove(tmp)
+		return utils.Wrapf(err, "Cannot rename %s", tmp)
+	}
+	return s.fs.SyncDir(s.dir)
 }
 
 func (s *fileStateStorage) Store(state *State) error {
-	utils.CreateDirIfNotExists(s.dir)
+	mode := state.Config.DirMode
+	if mode == 0 {
+		mode = DefaultDirMode
+	}
+	err := utils.MkdirAll(s.fs, s.dir, mode)
+	if err != nil {
+		return err
+	}
 	b, err := s.marshaler.marshal(state)
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(path.Join(s.dir, s.filename(state.FullName)), b, os.ModePerm)
+	return s.storeFile(s.filename(state.FullName), b)
 }
 
 func (s *fileStateStorage) loadFromFile(filename string) (*State, error) {
-	b, err := ioutil.ReadFile(filename)
+	b, err := s.fs.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 	return s.marshaler.unmarshal(b)
 }
 
+// load reads a state file; if it's missing or corrupted the backup is used and
+// restored.
+func (s *fileStateStorage) load(filename string) (*State, error) {
+	state, err := s.loadFromFile(filename)
+	if err == nil {
+		return state, nil
+	}
+	backup, berr := s.loadFromFile(backupFilename(filename))
+	if berr != nil {
+		return nil, err
+	}
+	b, berr := s.marshaler.marshal(backup)
+	if berr == nil {
+		tmp, berr := s.writeTemp(filename, b)
+		if berr == nil && s.fs.Rename(tmp, filename) == nil {
+			s.fs.SyncDir(s.dir)
+		} else if berr == nil {
+			s.fs.Remove(tmp)
+		}
+	}
+	return backup, nil
+}
+
 func (s *fileStateStorage) Load(fullName string) (*State, error) {
-	filename := path.Join(s.dir, s.filename(fullName))
-	return s.loadFromFile(filename)
+	return s.load(s.filename(fullName))
 }
 
 func (s *fileStateStorage) Remove(fullName string) error {
-	filename := path.Join(s.dir, s.filename(fullName))
-	return os.Remove(filename)
+	filename := s.filename(fullName)
+	err := s.fs.Remove(filename)
+	if err != nil && !(os.IsNotExist(err) && utils.ExistsIn(s.fs, backupFilename(filename))) {
+		return err
+	}
+	err = s.fs.Remove(backupFilename(filename))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	files, err := s.fs.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := path.Join(s.dir, file.Name())
+		if !strings.HasPrefix(name, tempPrefix(filename)) {
+			continue
+		}
+		if err := s.fs.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *fileStateStorage) List() ([]*State, error) {
-	if !utils.Exists(s.dir) {
+	if !utils.ExistsIn(s.fs, s.dir) {
 		return []*State{}, nil
 	}
-	files, err := ioutil.ReadDir(s.dir)
+	files, err := s.fs.ReadDir(s.dir)
 	if err != nil {
 		return nil, err
 	}
-	states := make([]*State, 0)
+	names := make([]string, 0)
+	seen := make(map[string]bool)
 	for _, file := range files {
-		state, err := s.loadFromFile(path.Join(s.dir, file.Name()))
+		name := strings.TrimSuffix(file.Name(), ".bak")
+		if strings.HasSuffix(name, ".json") && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	states := make([]*State, 0)
+	for _, name := range names {
+		state, err := s.load(path.Join(s.dir, name))
 		if err == nil {
 			states = append(states, state)
 		} else {
@@ -89,12 +213,23 @@ func MustCreateHomeDirStateStorage() StateStorage {
 }
 
 func newFileStateStorage(dir string) (*fileStateStorage, error) {
+	return newFileStateStorageIn(fsys.NewOSFileSystem(), dir)
+}
+
+func newFileStateStorageIn(fs fsys.FileSystem, dir string) (*fileStateStorage, error) {
 	return &fileStateStorage{
+		fs:        fs,
 		dir:       dir,
 		marshaler: &jsonStateMarshaler{},
 	}, nil
 }
 
+// NewFileStateStorage returns a storage that keeps a JSON file for each state
+// in the given directory.
+func NewFileStateStorage(fs fsys.FileSystem, dir string) (StateStorage, error) {
+	return newFileStateStorageIn(fs, dir)
+}
+
 func List() ([]*State, error) {
 	storage, err := NewHomeDirStateStorage()
 	if err != nil {
@@ -112,29 +247,52 @@ func Remove(name string) error {
 }
 
 type mapStorage struct {
-	states map[string]*State
+	mu     sync.Mutex
+	states map[string]State
 }
 
 func (s *mapStorage) Store(state *State) error {
-	s.states[state.FullName] = state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state.FullName] = *state
 	return nil
 }
 
 func (s *mapStorage) Load(fullName string) (*State, error) {
-	return s.states[fullName], nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state, ok := s.states[fullName]; ok {
+		return &state, nil
+	}
+	return nil, utils.Wrapf(os.ErrNotExist, "Cannot find state for %s", fullName)
 }
 
 func (s *mapStorage) List() ([]*State, error) {
-	return nil, nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states := make([]*State, 0, len(s.states))
+	for _, state := range s.states {
+		state := state
+		states = append(states, &state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].FullName < states[j].FullName })
+	return states, nil
 }
 
 func (s *mapStorage) Remove(fullName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.states[fullName]; !ok {
+		return utils.Wrapf(os.ErrNotExist, "Cannot find state for %s", fullName)
+	}
 	delete(s.states, fullName)
 	return nil
 }
 
+// NewMapStorage returns an in-memory storage; states are copied when stored
+// and loaded.
 func NewMapStorage() StateStorage {
 	return &mapStorage{
-		states: make(map[string]*State),
+		states: make(map[string]State),
 	}
 }
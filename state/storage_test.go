@@ -1,10 +1,13 @@
 package state
 
 import (
+	"errors"
 	"os"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/lorenzobenvenuti/loco/fsys"
 	"github.com/lorenzobenvenuti/loco/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -100,3 +103,179 @@ func TestStoreSuccessfullyRemoveStates(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 0, len(states))
 }
+
+func TestStoreInMemory(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	s, err := NewFileStateStorage(fs, "/home/user/.loco/logfiles")
+	assert.NoError(t, err)
+	states, err := s.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(states))
+	expected := &State{
+		FullName: "/path/to/file",
+		Counter:  42,
+		Config:   Config{Interval: time.Hour, Suffix: "%c"},
+	}
+	assert.NoError(t, s.Store(expected))
+	actual, err := s.Load("/path/to/file")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	states, err = s.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []*State{expected}, states)
+}
+
+func TestMapStorage(t *testing.T) {
+	s := NewMapStorage()
+	_, err := s.Load("/path/to/file")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	s1 := &State{FullName: "/path/to/file2", Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	s2 := &State{FullName: "/path/to/file1", Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	assert.NoError(t, s.Store(s1))
+	assert.NoError(t, s.Store(s2))
+	s1.Counter = 42
+	loaded, err := s.Load("/path/to/file2")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, loaded.Counter, "Stored states are copies")
+	states, err := s.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []*State{s2, loaded}, states)
+	assert.NoError(t, s.Remove("/path/to/file1"))
+	assert.Error(t, s.Remove("/path/to/file1"))
+	states, err = s.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []*State{loaded}, states)
+}
+
+func TestLoadOfAMissingStateIsNotExist(t *testing.T) {
+	_, s := newMemStorage(t)
+	_, err := s.Load("/path/to/file")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func newMemStorage(t *testing.T) (*fsys.MemFileSystem, *fileStateStorage) {
+	fs := fsys.NewMemFileSystem()
+	s, err := newFileStateStorageIn(fs, "/states")
+	assert.NoError(t, err)
+	return fs, s
+}
+
+func TestStoreCreatesTheDirectoryWithTheConfiguredMode(t *testing.T) {
+	fs, s := newMemStorage(t)
+	assert.NoError(t, s.Store(&State{FullName: "/path/to/file", Config: Config{Suffix: "%c", DirMode: 0700}}))
+	info, err := fs.Stat("/states")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+func TestStoreKeepsABackup(t *testing.T) {
+	fs, s := newMemStorage(t)
+	first := &State{FullName: "/path/to/file", Counter: 1, Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	second := &State{FullName: "/path/to/file", Counter: 2, Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	assert.NoError(t, s.Store(first))
+	assert.NoError(t, s.Store(second))
+	filename := s.filename("/path/to/file")
+	backup, err := s.loadFromFile(backupFilename(filename))
+	assert.NoError(t, err)
+	assert.Equal(t, first, backup)
+	files, err := fs.ReadDir("/states")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(files), "No temporary file is left")
+}
+
+func TestStoreKeepsABackupWithoutReadingTheState(t *testing.T) {
+	fs, s := newMemStorage(t)
+	first := &State{FullName: "/path/to/file", Counter: 1, Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	assert.NoError(t, s.Store(first))
+	filename := s.filename("/path/to/file")
+	fs.InjectFault(fsys.OpOpen, filename, syscall.EACCES)
+	assert.NoError(t, s.Store(&State{FullName: "/path/to/file", Counter: 2}))
+	fs.ClearFaults()
+	backup, err := s.loadFromFile(backupFilename(filename))
+	assert.NoError(t, err)
+	assert.Equal(t, first, backup)
+}
+
+func TestWriteTempUsesADifferentFileForEachWriter(t *testing.T) {
+	fs, s := newMemStorage(t)
+	assert.NoError(t, fs.MkdirAll("/states", 0755))
+	filename := s.filename("/path/to/file")
+	first, err := s.writeTemp(filename, []byte("first"))
+	assert.NoError(t, err)
+	second, err := s.writeTemp(filename, []byte("second"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	b, err := fs.ReadFile(first)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", string(b))
+}
+
+func TestStoreSurvivesACrash(t *testing.T) {
+	fs, s := newMemStorage(t)
+	expected := &State{FullName: "/path/to/file", Counter: 1, Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	assert.NoError(t, s.Store(expected))
+	fs.Crash()
+	actual, err := s.Load("/path/to/file")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestLoadRecoversACorruptedState(t *testing.T) {
+	fs, s := newMemStorage(t)
+	first := &State{FullName: "/path/to/file", Counter: 1, Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	second := &State{FullName: "/path/to/file", Counter: 2, Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	assert.NoError(t, s.Store(first))
+	assert.NoError(t, s.Store(second))
+	filename := s.filename("/path/to/file")
+	assert.NoError(t, fs.WriteFile(filename, []byte(`{"FullName":"/pa`), 0644))
+	actual, err := s.Load("/path/to/file")
+	assert.NoError(t, err)
+	assert.Equal(t, first, actual)
+	repaired, err := s.loadFromFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, first, repaired)
+	states, err := s.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []*State{first}, states)
+}
+
+func TestLoadRecoversAMissingState(t *testing.T) {
+	fs, s := newMemStorage(t)
+	first := &State{FullName: "/path/to/file", Counter: 1, Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	assert.NoError(t, s.Store(first))
+	filename := s.filename("/path/to/file")
+	assert.NoError(t, fs.Rename(filename, backupFilename(filename)))
+	states, err := s.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []*State{first}, states)
+	actual, err := s.Load("/path/to/file")
+	assert.NoError(t, err)
+	assert.Equal(t, first, actual)
+}
+
+func TestStoreFailureLeavesThePreviousState(t *testing.T) {
+	fs, s := newMemStorage(t)
+	first := &State{FullName: "/path/to/file", Counter: 1, Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	assert.NoError(t, s.Store(first))
+	fs.InjectFault(fsys.OpWrite, "", syscall.ENOSPC)
+	assert.Error(t, s.Store(&State{FullName: "/path/to/file", Counter: 2}))
+	actual, err := s.Load("/path/to/file")
+	assert.NoError(t, err)
+	assert.Equal(t, first, actual)
+}
+
+func TestRemoveDeletesTheBackup(t *testing.T) {
+	fs, s := newMemStorage(t)
+	state := &State{FullName: "/path/to/file", Config: Config{Interval: time.Hour, Suffix: "%c"}}
+	assert.NoError(t, s.Store(state))
+	assert.NoError(t, s.Store(state))
+	_, err := s.writeTemp(s.filename("/path/to/file"), []byte("leftover"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Remove("/path/to/file"))
+	files, err := fs.ReadDir("/states")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(files))
+	_, err = s.Load("/path/to/file")
+	assert.Error(t, err)
+	assert.Error(t, s.Remove("/path/to/file"))
+}
@@ -0,0 +1,194 @@
+package status
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/archives"
+	"github.com/lorenzobenvenuti/loco/defaults"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+)
+
+// Setting is a value of the configuration and where it comes from.
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Status describes a log file: its configuration, its rotations, what has been
+// written since the last rotation, its archives and the pid of the process
+// collecting it, if any.
+type Status struct {
+	File         string     `json:"file"`
+	Settings     []Setting  `json:"settings"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty"`
+	RotatedAt    *time.Time `json:"rotatedAt,omitempty"`
+	NextRotation *time.Time `json:"nextRotation,omitempty"`
+	Rotations    int        `json:"rotations"`
+	Bytes        int64      `json:"bytes"`
+	Lines        int64      `json:"lines"`
+	Archives     int        `json:"archives"`
+	ArchivesSize int64      `json:"archivesSize"`
+	CollectorPid int        `json:"collectorPid,omitempty"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// setting returns the configured value, or the built-in value used by the
+// writer when the configuration leaves it unset.
+func setting(name string, value string, builtin string) Setting {
+	if value == "" {
+		return Setting{name, builtin, defaults.SourceBuiltin}
+	}
+	return Setting{name, value, defaults.SourceFile}
+}
+
+func settings(c *state.Config) []Setting {
+	source := func(key string) string {
+		if s, ok := c.Sources[key]; ok {
+			return s
+		}
+		return defaults.SourceFile
+	}
+	mode := func(m os.FileMode) string {
+		if m == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%04o", uint32(m))
+	}
+	retention := ""
+	if c.Retention != 0 {
+		retention = strconv.Itoa(c.Retention)
+	}
+	utc := ""
+	if c.UTC {
+		utc = "true"
+	}
+	return []Setting{
+		{"interval", c.Interval.String(), source("interval")},
+		{"suffix", c.Suffix, source("suffix")},
+		setting("naming", c.Naming, state.NamingSuffix),
+		setting("retention", retention, "0"),
+		setting("collision", c.Collision, state.CollisionUnique),
+		setting("utc", utc, "false"),
+		setting("archive-dir", c.ArchiveDir, "."),
+		setting("dir-mode", mode(c.DirMode), "0755"),
+		setting("file-mode", mode(c.FileMode), "0644"),
+		setting("owner", c.Owner, "-"),
+		setting("group", c.Group, "-"),
+	}
+}
+
+func countLines(r io.Reader) (int64, error) {
+	var lines int64
+	br := bufio.NewReader(r)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := br.Read(buf)
+		lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+}
+
+// Get collects the status of a log file; collectorPid is zero if no process
+// is collecting it.
+func Get(fs fsys.FileSystem, s *state.State, collectorPid int) (*Status, error) {
+	st := &Status{
+		File:         s.FullName,
+		Settings:     settings(&s.Config),
+		CreatedAt:    optionalTime(s.CreatedAt),
+		RotatedAt:    optionalTime(s.RotatedAt),
+		Rotations:    s.Counter,
+		CollectorPid: collectorPid,
+	}
+	if !s.RotatedAt.IsZero() {
+		st.NextRotation = optionalTime(s.RotatedAt.Add(s.Config.Interval))
+	}
+	f, err := fs.OpenFile(s.FullName, os.O_RDONLY, 0)
+	if err == nil {
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		st.Bytes = info.Size()
+		st.Lines, err = countLines(f)
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	list, err := archives.List(fs, s)
+	if err != nil {
+		return nil, err
+	}
+	st.Archives = len(list)
+	for _, a := range list {
+		st.ArchivesSize += a.Size
+	}
+	return st, nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC822)
+}
+
+// WriteText writes the status as a table; now is used to tell whether the
+// next rotation is overdue.
+func (st *Status) WriteText(w io.Writer, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', tabwriter.TabIndent)
+	fmt.Fprintf(tw, "File:\t%s\n", st.File)
+	collector := "no"
+	if st.CollectorPid != 0 {
+		collector = fmt.Sprintf("yes (pid %d)", st.CollectorPid)
+	}
+	fmt.Fprintf(tw, "Collector attached:\t%s\n", collector)
+	fmt.Fprintf(tw, "Created at:\t%s\n", formatTime(st.CreatedAt))
+	fmt.Fprintf(tw, "Rotated at:\t%s\n", formatTime(st.RotatedAt))
+	next := "at the first write"
+	if st.NextRotation != nil {
+		next = formatTime(st.NextRotation)
+		if st.NextRotation.Before(now) {
+			next += " (at the next write)"
+		}
+	}
+	fmt.Fprintf(tw, "Next rotation:\t%s\n", next)
+	fmt.Fprintf(tw, "Rotations:\t%d\n", st.Rotations)
+	fmt.Fprintf(tw, "Since last rotation:\t%d bytes, %d lines\n", st.Bytes, st.Lines)
+	fmt.Fprintf(tw, "Archives:\t%d (%d bytes)\n", st.Archives, st.ArchivesSize)
+	fmt.Fprintf(tw, "\nSETTING\tVALUE\tSOURCE\n")
+	for _, s := range st.Settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, s.Value, s.Source)
+	}
+	return tw.Flush()
+}
+
+func (st *Status) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	e.SetEscapeHTML(false)
+	return e.Encode(st)
+}
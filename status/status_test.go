@@ -0,0 +1,107 @@
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	fs.WriteFile("/logs/app.log", []byte("first\nsecond\n"), 0644)
+	fs.WriteFile("/logs/app.20181209.log", []byte("1234"), 0644)
+	fs.WriteFile("/logs/app.20181210.log", []byte("123456"), 0644)
+	s := &state.State{
+		FullName:  "/logs/app.log",
+		CreatedAt: time.Date(2018, 12, 9, 0, 0, 0, 0, time.UTC),
+		RotatedAt: time.Date(2018, 12, 10, 0, 0, 0, 0, time.UTC),
+		Counter:   2,
+		Config: state.Config{
+			Interval:  24 * time.Hour,
+			Suffix:    "%Y%m%d",
+			UTC:       true,
+			Retention: 5,
+			Sources:   map[string]string{"interval": "file", "suffix": "env"},
+		},
+	}
+	st, err := Get(fs, s, 42)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2018, 12, 11, 0, 0, 0, 0, time.UTC), *st.NextRotation)
+	assert.Equal(t, int64(13), st.Bytes)
+	assert.Equal(t, int64(2), st.Lines)
+	assert.Equal(t, 2, st.Archives)
+	assert.Equal(t, int64(10), st.ArchivesSize)
+	assert.Equal(t, 2, st.Rotations)
+	assert.Equal(t, 42, st.CollectorPid)
+	assert.Contains(t, st.Settings, Setting{"interval", "24h0m0s", "file"})
+	assert.Contains(t, st.Settings, Setting{"suffix", "%Y%m%d", "env"})
+	assert.Contains(t, st.Settings, Setting{"retention", "5", "file"})
+	assert.Contains(t, st.Settings, Setting{"naming", "suffix", "builtin"})
+}
+
+func TestGetWithoutLogFile(t *testing.T) {
+	s := &state.State{
+		FullName: "/logs/app.log",
+		Config:   state.Config{Interval: 24 * time.Hour, Suffix: "%Y%m%d", UTC: true},
+	}
+	st, err := Get(fsys.NewMemFileSystem(), s, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, st.NextRotation)
+	assert.Equal(t, int64(0), st.Bytes)
+	assert.Equal(t, 0, st.Archives)
+}
+
+func TestWriteText(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	fs.WriteFile("/logs/app.log", []byte("line\n"), 0644)
+	s := &state.State{
+		FullName:  "/logs/app.log",
+		RotatedAt: time.Date(2018, 12, 10, 0, 0, 0, 0, time.UTC),
+		Config: state.Config{
+			Interval: 24 * time.Hour,
+			Suffix:   "%Y%m%d",
+			UTC:      true,
+			Sources:  map[string]string{"suffix": "env"},
+		},
+	}
+	st, _ := Get(fs, s, 0)
+	var b bytes.Buffer
+	err := st.WriteText(&b, time.Date(2018, 12, 12, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Contains(t, b.String(), "Collector attached:  no\n")
+	assert.Contains(t, b.String(), "Next rotation:       11 Dec 18 00:00 UTC (at the next write)\n")
+	assert.Contains(t, b.String(), "Since last rotation: 5 bytes, 1 lines\n")
+	assert.Contains(t, b.String(), "suffix      %Y%m%d  env\n")
+}
+
+func TestWriteJSON(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	fs.WriteFile("/logs/app.log", []byte("line\n"), 0644)
+	s := &state.State{
+		FullName:  "/logs/app.log",
+		RotatedAt: time.Date(2018, 12, 10, 0, 0, 0, 0, time.UTC),
+		Config: state.Config{
+			Interval: 24 * time.Hour,
+			Suffix:   "%Y%m%d",
+			UTC:      true,
+			Sources:  map[string]string{"suffix": "env"},
+		},
+	}
+	st, _ := Get(fs, s, 7)
+	var b bytes.Buffer
+	assert.NoError(t, st.WriteJSON(&b))
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &m))
+	assert.Equal(t, "/logs/app.log", m["file"])
+	assert.Equal(t, float64(7), m["collectorPid"])
+	assert.Equal(t, "2018-12-11T00:00:00Z", m["nextRotation"])
+	assert.Equal(t, float64(5), m["bytes"])
+}
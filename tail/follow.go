@@ -0,0 +1,104 @@
+package tail
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// follower reads a file by name: when the name points to another file, because
+// the file was renamed or a symlink was switched, the rest of the old file is
+// read and the new one is opened; when the file is truncated, it's read again
+// from the beginning.
+type follower struct {
+	fs     fsys.FileSystem
+	name   string
+	file   fsys.File
+	info   os.FileInfo
+	offset int64
+}
+
+func newFollower(fs fsys.FileSystem, name string) *follower {
+	return &follower{fs: fs, name: name}
+}
+
+func (f *follower) open() error {
+	file, err := f.fs.OpenFile(f.name, os.O_RDONLY, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return utils.Wrapf(err, "Cannot open %s", f.name)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.info, f.offset = file, info, 0
+	return nil
+}
+
+func (f *follower) close() {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+}
+
+func (f *follower) Read(p []byte) (int, error) {
+	n, err := f.file.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *follower) drain(w io.Writer) error {
+	if f.file == nil {
+		return nil
+	}
+	_, err := io.Copy(w, f)
+	return err
+}
+
+func (f *follower) poll(w io.Writer) error {
+	err := f.drain(w)
+	if err != nil {
+		return err
+	}
+	info, err := f.fs.Stat(f.name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if f.file != nil && fsys.SameFile(info, f.info) && info.Size() >= f.offset {
+		return nil
+	}
+	f.close()
+	err = f.open()
+	if err != nil {
+		return err
+	}
+	return f.drain(w)
+}
+
+func (f *follower) follow(ctx context.Context, w io.Writer, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := f.poll(w)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package tail
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func appendTo(t *testing.T, fs fsys.FileSystem, name string, content string) {
+	f, err := fs.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+}
+
+func TestFollowAcrossRenames(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	appendTo(t, fs, "/app.log", "a\n")
+	f := newFollower(fs, "/app.log")
+	defer f.close()
+	assert.NoError(t, f.open())
+	var b bytes.Buffer
+	assert.NoError(t, f.poll(&b))
+	appendTo(t, fs, "/app.log", "b\n")
+	fs.Rename("/app.log", "/app.1.log")
+	assert.NoError(t, f.poll(&b))
+	appendTo(t, fs, "/app.log", "c\n")
+	assert.NoError(t, f.poll(&b))
+	assert.Equal(t, "a\nb\nc\n", b.String())
+}
+
+func TestFollowAfterTruncation(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	appendTo(t, fs, "/app.log", "aaaa\n")
+	f := newFollower(fs, "/app.log")
+	defer f.close()
+	assert.NoError(t, f.open())
+	var b bytes.Buffer
+	assert.NoError(t, f.poll(&b))
+	fs.WriteFile("/app.log", []byte("b\n"), 0644)
+	assert.NoError(t, f.poll(&b))
+	assert.Equal(t, "aaaa\nb\n", b.String())
+}
+
+func TestFollowAFileCreatedLater(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	f := newFollower(fs, "/app.log")
+	defer f.close()
+	assert.NoError(t, f.open())
+	var b bytes.Buffer
+	assert.NoError(t, f.poll(&b))
+	appendTo(t, fs, "/app.log", "a\n")
+	assert.NoError(t, f.poll(&b))
+	assert.Equal(t, "a\n", b.String())
+}
+
+func TestFollowSymlinkSwitches(t *testing.T) {
+	dir := utils.MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	fs := fsys.NewOSFileSystem()
+	link := path.Join(dir, "current.log")
+	appendTo(t, fs, path.Join(dir, "app.1.log"), "a\n")
+	assert.NoError(t, os.Symlink(path.Join(dir, "app.1.log"), link))
+	f := newFollower(fs, link)
+	defer f.close()
+	assert.NoError(t, f.open())
+	var b bytes.Buffer
+	assert.NoError(t, f.poll(&b))
+	appendTo(t, fs, path.Join(dir, "app.2.log"), "b\n")
+	assert.NoError(t, os.Remove(link))
+	assert.NoError(t, os.Symlink(path.Join(dir, "app.2.log"), link))
+	assert.NoError(t, f.poll(&b))
+	assert.Equal(t, "a\nb\n", b.String())
+}
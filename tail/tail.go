@@ -0,0 +1,94 @@
+package tail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/archives"
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/lorenzobenvenuti/loco/utils"
+)
+
+// lastLines returns the last n lines read from r, keeping their newlines.
+func lastLines(r io.Reader, n int) ([]string, error) {
+	ring := make([]string, 0, n)
+	start := 0
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" && n > 0 {
+			if len(ring) < n {
+				ring = append(ring, line)
+			} else {
+				ring[start] = line
+				start = (start + 1) % n
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(ring[start:], ring[:start]...), nil
+}
+
+func archiveLines(fs fsys.FileSystem, f *archives.File, n int) ([]string, error) {
+	r, err := archives.Open(fs, f)
+	if err != nil {
+		return nil, utils.Wrapf(err, "Cannot open %s", f.Path)
+	}
+	defer r.Close()
+	return lastLines(r, n)
+}
+
+// Tail writes the last n lines of a log to w, reading the newest archives if
+// the log file is shorter. If follow is set, it then writes what's appended to
+// the log file, across rotations, until ctx is done.
+func Tail(ctx context.Context, w io.Writer, fs fsys.FileSystem, s *state.State, n int, follow bool, interval time.Duration) error {
+	if n < 0 {
+		return fmt.Errorf("Invalid number of lines %d", n)
+	}
+	history, err := archives.History(fs, s)
+	if err != nil {
+		return err
+	}
+	f := newFollower(fs, s.FullName)
+	defer f.close()
+	err = f.open()
+	if err != nil {
+		return err
+	}
+	var lines []string
+	if f.file != nil {
+		lines, err = lastLines(f, n)
+		if err != nil {
+			return utils.Wrapf(err, "Cannot read %s", s.FullName)
+		}
+	}
+	for i := len(history) - 1; i >= 0 && len(lines) < n; i-- {
+		if history[i].Path == s.FullName {
+			continue
+		}
+		older, err := archiveLines(fs, history[i], n-len(lines))
+		if err != nil {
+			return err
+		}
+		lines = append(older, lines...)
+	}
+	for _, line := range lines {
+		_, err := io.WriteString(w, line)
+		if err != nil {
+			return err
+		}
+	}
+	if !follow {
+		return nil
+	}
+	return f.follow(ctx, w, interval)
+}
@@ -0,0 +1,80 @@
+package tail
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/lorenzobenvenuti/loco/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLastLines(t *testing.T) {
+	for n, expected := range map[int][]string{
+		0: {},
+		2: {"c\n", "d"},
+		4: {"a\n", "b\n", "c\n", "d"},
+		9: {"a\n", "b\n", "c\n", "d"},
+	} {
+		lines, err := lastLines(strings.NewReader("a\nb\nc\nd"), n)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, lines)
+	}
+}
+
+func TestTailSpansIntoArchives(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	fs.WriteFile("/logs/app.1.log", []byte("one\ntwo\n"), 0644)
+	fs.WriteFile("/logs/app.2.log", []byte("three\nfour\n"), 0644)
+	fs.WriteFile("/logs/app.log", []byte("five\n"), 0644)
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%c"}}
+	for n, expected := range map[int]string{
+		1:  "five\n",
+		3:  "three\nfour\nfive\n",
+		4:  "two\nthree\nfour\nfive\n",
+		10: "one\ntwo\nthree\nfour\nfive\n",
+	} {
+		var b bytes.Buffer
+		err := Tail(context.Background(), &b, fs, s, n, false, time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, b.String())
+	}
+}
+
+func TestTailWithoutLogFile(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	fs.WriteFile("/logs/app.1.log", []byte("one\ntwo\n"), 0644)
+	fs.WriteFile("/logs/app.2.log", []byte("three\nfour\n"), 0644)
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%c"}}
+	var b bytes.Buffer
+	err := Tail(context.Background(), &b, fs, s, 1, false, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "four\n", b.String())
+}
+
+func TestTailRejectsANegativeNumberOfLines(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%c"}}
+	var b bytes.Buffer
+	err := Tail(context.Background(), &b, fs, s, -1, false, time.Millisecond)
+	assert.Error(t, err)
+	assert.Equal(t, "", b.String())
+}
+
+func TestTailFollowsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	fs.WriteFile("/logs/app.log", []byte("four\nfive\n"), 0644)
+	s := &state.State{FullName: "/logs/app.log", Config: state.Config{Suffix: "%c"}}
+	var b bytes.Buffer
+	err := Tail(ctx, &b, fs, s, 1, true, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "five\n", b.String())
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+)
+
+// PartName is the temporary file used while copying a file to name.
+func PartName(name string) string {
+	return name + ".part"
+}
+
+func copyFile(fs fsys.FileSystem, from string, to string) error {
+	info, err := fs.Stat(from)
+	if err != nil {
+		return err
+	}
+	src, err := fs.OpenFile(from, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	f, err := fs.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, src)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// MkdirAll creates dir and its missing parents with mode. Since MkdirAll is
+// subject to the umask, the mode of the created directories is set explicitly;
+// the parent of each created directory is synced so that the new entries
+// survive a crash.
+func MkdirAll(fs fsys.FileSystem, dir string, mode os.FileMode) error {
+	missing := make([]string, 0)
+	for d := path.Clean(dir); !ExistsIn(fs, d); d = path.Dir(d) {
+		missing = append(missing, d)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	err := fs.MkdirAll(dir, mode)
+	if err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := fs.Chmod(missing[i], mode); err != nil {
+			return err
+		}
+		if err := fs.SyncDir(path.Dir(missing[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Move renames from to to, creating the directory of to with dirMode if
+// needed. When to is on another file system, from is copied to a temporary
+// file next to to, which is then renamed, and finally removed.
+func Move(fs fsys.FileSystem, from string, to string, dirMode os.FileMode) error {
+	dir := path.Dir(to)
+	err := MkdirAll(fs, dir, dirMode)
+	if err != nil {
+		return Wrapf(err, "Cannot create directory %s", dir)
+	}
+	err = fs.Rename(from, to)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	part := PartName(to)
+	err = copyFile(fs, from, part)
+	if err != nil {
+		fs.Remove(part)
+		return Wrapf(err, "Cannot copy %s", from)
+	}
+	err = fs.Rename(part, to)
+	if err != nil {
+		return err
+	}
+	return fs.Remove(from)
+}
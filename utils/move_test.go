@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveCreatesTheDirectory(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	fs.WriteFile("/logs/file.log", []byte("foo"), 0644)
+	assert.NoError(t, Move(fs, "/logs/file.log", "/archive/2018/file.log", 0750))
+	b, err := fs.ReadFile("/archive/2018/file.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(b))
+	assert.False(t, ExistsIn(fs, "/logs/file.log"))
+	info, _ := fs.Stat("/archive/2018")
+	assert.Equal(t, "drwxr-x---", info.Mode().String())
+}
+
+func TestMoveCopiesAcrossFileSystems(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	fs.MkdirAll("/logs", 0755)
+	fs.WriteFile("/logs/file.log", []byte("foo"), 0640)
+	fs.InjectFault(fsys.OpRename, "/logs/file.log", syscall.EXDEV)
+	assert.NoError(t, Move(fs, "/logs/file.log", "/archive/file.log", 0755))
+	b, err := fs.ReadFile("/archive/file.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(b))
+	assert.False(t, ExistsIn(fs, "/logs/file.log"))
+	assert.False(t, ExistsIn(fs, PartName("/archive/file.log")))
+	info, _ := fs.Stat("/archive/file.log")
+	assert.Equal(t, "-rw-r-----", info.Mode().String())
+}
+
+func TestMkdirAllIgnoresTheUmask(t *testing.T) {
+	dir := MustCreateTempDir()
+	defer os.RemoveAll(dir)
+	umask := syscall.Umask(022)
+	defer syscall.Umask(umask)
+	fs := fsys.NewOSFileSystem()
+	assert.NoError(t, MkdirAll(fs, path.Join(dir, "arch/2026"), 0770))
+	for _, d := range []string{"arch", "arch/2026"} {
+		info, err := os.Stat(path.Join(dir, d))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0770), info.Mode().Perm(), d)
+	}
+	info, _ := os.Stat(dir)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+func TestMkdirAllSurvivesACrash(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	assert.NoError(t, MkdirAll(fs, "/path/to/dir", 0700))
+	fs.Crash()
+	info, err := fs.Stat("/path/to/dir")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
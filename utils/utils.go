@@ -8,6 +8,9 @@ import (
 	"os"
 	"os/user"
 	"path"
+	"strconv"
+
+	"github.com/lorenzobenvenuti/loco/fsys"
 )
 
 func touch(file string) error {
@@ -20,20 +23,19 @@ func touch(file string) error {
 }
 
 func Exists(file string) bool {
-	if _, err := os.Stat(file); os.IsNotExist(err) {
+	return ExistsIn(fsys.NewOSFileSystem(), file)
+}
+
+func ExistsIn(fs fsys.FileSystem, file string) bool {
+	if _, err := fs.Stat(file); os.IsNotExist(err) {
 		return false
 	}
 	return true
 }
 
+// CreateDirIfNotExists creates dir and its missing parents with mode 0755.
 func CreateDirIfNotExists(dir string) error {
-	if !Exists(dir) {
-		err := os.MkdirAll(dir, os.ModePerm)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return MkdirAll(fsys.NewOSFileSystem(), dir, 0755)
 }
 
 func MD5(value string) string {
@@ -51,6 +53,10 @@ func (e *wrappedError) Error() string {
 	return fmt.Sprintf("%s: %s", e.message, e.err.Error())
 }
 
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
 func Wrap(err error, msg string) error {
 	return &wrappedError{
 		message: msg,
@@ -93,3 +99,27 @@ func AppDir() (string, error) {
 	}
 	return path.Join(dir, ".loco"), nil
 }
+
+// LookupUid returns the id of a user given its name or its id.
+func LookupUid(owner string) (int, error) {
+	if id, err := strconv.Atoi(owner); err == nil {
+		return id, nil
+	}
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// LookupGid returns the id of a group given its name or its id.
+func LookupGid(group string) (int, error) {
+	if id, err := strconv.Atoi(group); err == nil {
+		return id, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
@@ -7,6 +7,7 @@ import (
 	"path"
 	"testing"
 
+	"github.com/lorenzobenvenuti/loco/fsys"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,6 +28,13 @@ func TestTouchAndExists(t *testing.T) {
 	assert.True(t, Exists(file), "File must exist")
 }
 
+func TestExistsIn(t *testing.T) {
+	fs := fsys.NewMemFileSystem()
+	assert.False(t, ExistsIn(fs, "/path/to/dir"), "Directory must not exist")
+	assert.NoError(t, fs.MkdirAll("/path/to/dir", 0755))
+	assert.True(t, ExistsIn(fs, "/path/to/dir"), "Directory must exist")
+}
+
 func TestCreateDirIfNotExists(t *testing.T) {
 	temp := mustCreate(ioutil.TempDir("", ""))
 	defer os.RemoveAll(temp)
@@ -51,3 +59,19 @@ func TestWrapfError(t *testing.T) {
 	err := errors.New("First error")
 	assert.Equal(t, "A foo error occurred: First error", Wrapf(err, "A %s error occurred", "foo").Error())
 }
+
+func TestLookupUidAndGid(t *testing.T) {
+	uid, err := LookupUid("1234")
+	assert.NoError(t, err)
+	assert.Equal(t, 1234, uid)
+	uid, err = LookupUid("root")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, uid)
+	gid, err := LookupGid("0")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, gid)
+	_, err = LookupUid("no-such-user-here")
+	assert.Error(t, err)
+	_, err = LookupGid("no-such-group-here")
+	assert.Error(t, err)
+}